@@ -0,0 +1,153 @@
+// Package ability define las habilidades especiales de los miembros del
+// equipo y un registro declarativo para configurarlas sin tocar código.
+//
+// Una habilidad declara un disparador (estrellas, fracción de turnos,
+// probabilidad) y un efecto sobre el golpe (botín por turno, límite de
+// estrellas, turnos más rápidos, reducción de estrellas).
+package ability
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// State es la situación del golpe con la que se evalúan los disparadores.
+type State struct {
+	Stars      int32 // estrellas efectivas, ya descontada cualquier reducción
+	Turn       int32
+	TotalTurns int32
+	Rand       *rand.Rand // azar de la misión, para los disparadores con chance
+}
+
+// Effects son los parámetros del golpe que las habilidades pueden modificar.
+type Effects struct {
+	MaxStars      int32 // estrellas con las que el golpe fracasa
+	LootPerTurn   int32 // botín extra ganado en cada turno
+	TurnsPerTick  int32 // turnos que avanza el golpe en cada tick
+	StarReduction int32 // estrellas descontadas de cada notificación
+}
+
+// DefaultEffects son los efectos de un golpe sin habilidades activas.
+func DefaultEffects() Effects {
+	return Effects{MaxStars: 5, TurnsPerTick: 1}
+}
+
+// Ability es una habilidad especial. Se activa como mucho una vez por misión.
+type Ability interface {
+	// Triggered indica si la habilidad se activa en el estado actual.
+	Triggered(state State) bool
+	// Activate aplica la habilidad sobre los efectos del golpe y devuelve el
+	// mensaje que se registra en el log.
+	Activate(effects *Effects) string
+}
+
+// Trigger declara cuándo se activa una habilidad. Todos los criterios
+// indicados deben cumplirse; los que valen cero se ignoran.
+type Trigger struct {
+	Stars        int32   `yaml:"stars"`         // al menos estas estrellas
+	TurnFraction float64 `yaml:"turn_fraction"` // al menos esta fracción de turnos completada
+	Chance       int     `yaml:"chance"`        // probabilidad (%) en cada evaluación
+}
+
+// Effect declara cómo cambia el golpe al activarse una habilidad.
+type Effect struct {
+	LootPerTurn   int32 `yaml:"loot_per_turn"`  // botín extra por turno
+	MaxStars      int32 `yaml:"max_stars"`      // cambio del límite de estrellas
+	TurnSpeedup   int32 `yaml:"turn_speedup"`   // turnos extra por tick
+	StarReduction int32 `yaml:"star_reduction"` // estrellas descontadas
+}
+
+// Spec es una habilidad declarativa, tal como se escribe en la configuración.
+type Spec struct {
+	Name    string  `yaml:"name"`
+	Message string  `yaml:"message"`
+	Trigger Trigger `yaml:"trigger"`
+	Effect  Effect  `yaml:"effect"`
+}
+
+func (s Spec) Triggered(state State) bool {
+	t := s.Trigger
+	if t.Stars > 0 && state.Stars < t.Stars {
+		return false
+	}
+	if t.TurnFraction > 0 && float64(state.Turn) < t.TurnFraction*float64(state.TotalTurns) {
+		return false
+	}
+	if t.Chance > 0 && state.Rand.Intn(100) >= t.Chance {
+		return false
+	}
+	return true
+}
+
+func (s Spec) Activate(effects *Effects) string {
+	effects.LootPerTurn += s.Effect.LootPerTurn
+	effects.MaxStars += s.Effect.MaxStars
+	effects.TurnsPerTick += s.Effect.TurnSpeedup
+	effects.StarReduction += s.Effect.StarReduction
+
+	if s.Message != "" {
+		return s.Message
+	}
+	return fmt.Sprintf(" ¡%s activado!", s.Name)
+}
+
+// Registry asocia nombres a habilidades declarativas.
+type Registry map[string]Spec
+
+// Builtin devuelve las habilidades históricas de Franklin y Trevor.
+func Builtin() Registry {
+	return Registry{
+		"chop": {
+			Name:    "Chop",
+			Message: " ¡Chop activado! Generando $1000 extra por turno",
+			Trigger: Trigger{Stars: 3},
+			Effect:  Effect{LootPerTurn: 1000},
+		},
+		"fury": {
+			Name:    "Furia de Trevor",
+			Message: " ¡Furia de Trevor activada! Límite aumentado a 7 estrellas",
+			Trigger: Trigger{Stars: 5},
+			Effect:  Effect{MaxStars: 2},
+		},
+	}
+}
+
+// Merge devuelve un registro con las habilidades de r más las de specs, que
+// reemplazan a las de r con el mismo nombre.
+func (r Registry) Merge(specs map[string]Spec) Registry {
+	merged := make(Registry, len(r)+len(specs))
+	for key, spec := range r {
+		merged[key] = spec
+	}
+	for key, spec := range specs {
+		if spec.Name == "" {
+			spec.Name = key
+		}
+		merged[key] = spec
+	}
+	return merged
+}
+
+// Resolve busca las habilidades indicadas por nombre.
+func (r Registry) Resolve(names []string) ([]Ability, error) {
+	abilities := make([]Ability, 0, len(names))
+	for _, key := range names {
+		spec, ok := r[key]
+		if !ok {
+			return nil, fmt.Errorf("habilidad desconocida %q (disponibles: %s)", key, strings.Join(r.names(), ", "))
+		}
+		abilities = append(abilities, spec)
+	}
+	return abilities, nil
+}
+
+func (r Registry) names() []string {
+	names := make([]string, 0, len(r))
+	for key := range r {
+		names = append(names, key)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,33 @@
+// Package crew implementa el servidor genérico de un miembro del equipo.
+//
+// Franklin, Trevor y cualquier miembro nuevo comparten la mecánica de
+// distracción y golpe; solo cambian su nombre, sus habilidades especiales
+// (ver el paquete ability) y el imprevisto que puede arruinar una distracción.
+package crew
+
+import (
+	"math/rand"
+)
+
+// Mishap decide si un imprevisto arruina la distracción en el turno indicado
+// y devuelve su descripción. rng es el azar de la misión.
+type Mishap func(rng *rand.Rand, turn, totalTurns int32) (string, bool)
+
+// HalfwayMishap devuelve un imprevisto con probabilidad percent%, que solo
+// puede ocurrir a mitad de la distracción.
+func HalfwayMishap(percent int, message string) Mishap {
+	return func(rng *rand.Rand, turn, totalTurns int32) (string, bool) {
+		if turn == totalTurns/2 && rng.Intn(100) < percent {
+			return message, true
+		}
+		return "", false
+	}
+}
+
+// Profile describe a un miembro del equipo.
+type Profile struct {
+	Name           string   // nombre del personaje, también usado en la cola de estrellas
+	Abilities      []string // habilidades por defecto, por nombre en el registro
+	Mishap         Mishap   // nil si la distracción no tiene imprevistos
+	PaymentMessage string   // respuesta cuando el pago es correcto
+}
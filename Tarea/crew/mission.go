@@ -0,0 +1,124 @@
+package crew
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"Tarea/ability"
+	pb "Tarea/proto"
+	"Tarea/starbus"
+)
+
+// mission guarda el estado de una misión asignada a un miembro del equipo.
+//
+// Todos los campos tras mu se leen y escriben con mu tomado; los workers y
+// los handlers gRPC corren en goroutines distintas. ctx se cancela cuando la
+// misión termina o se aborta, deteniendo workers y consumidor de estrellas.
+type mission struct {
+	id       string
+	notifier statusNotifier
+	ctx      context.Context
+	cancel   context.CancelFunc
+	turn     time.Duration
+	ended    atomic.Int64 // UnixNano en que se canceló ctx; 0 si sigue activa
+	kind     string       // distraction o golpe
+	seed     int64        // semilla del pedido que la inició; 0 si no trajo
+
+	mu             sync.Mutex
+	currentTurns   int32
+	totalTurns     int32
+	isWorking      bool
+	missionFailed  bool
+	missionSuccess bool
+	aborted        bool
+	rawStars       int32 // última notificación recibida, sin reducciones
+	currentStars   int32
+	extraLoot      int32
+	activated      []bool   // por habilidad del miembro
+	triggered      []string // mensajes de las habilidades activadas, en orden
+	effects        ability.Effects
+	baseLoot       int32
+	finalLoot      int32
+	rng            *rand.Rand     // azar de la misión (imprevistos y habilidades)
+	pending        []starbus.Star // estrellas que llegaron antes de su turno
+}
+
+func newMission(id string, turn time.Duration, abilities int, rng *rand.Rand) *mission {
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &mission{
+		id:        id,
+		ctx:       ctx,
+		turn:      turn,
+		activated: make([]bool, abilities),
+		effects:   ability.DefaultEffects(),
+		rng:       rng,
+	}
+	m.cancel = func() {
+		cancel()
+		m.ended.CompareAndSwap(0, time.Now().UnixNano())
+	}
+	return m
+}
+
+// expired indica si la misión terminó hace más de retention.
+func (m *mission) expired(now time.Time, retention time.Duration) bool {
+	ended := m.ended.Load()
+	return ended != 0 && now.Sub(time.Unix(0, ended)) > retention
+}
+
+// finished indica si la misión ya tiene un resultado. Requiere m.mu tomado.
+func (m *mission) finished() bool {
+	return m.missionSuccess || m.missionFailed || m.aborted
+}
+
+// setStars registra una notificación de estrellas aplicando la reducción
+// vigente. Requiere m.mu tomado.
+func (m *mission) setStars(raw int32) {
+	m.rawStars = raw
+	m.currentStars = raw - m.effects.StarReduction
+	if m.currentStars < 0 {
+		m.currentStars = 0
+	}
+}
+
+// sleepTurn espera un turno; devuelve false si la misión fue cancelada.
+func (m *mission) sleepTurn() bool {
+	select {
+	case <-m.ctx.Done():
+		return false
+	case <-time.After(m.turn):
+		return true
+	}
+}
+
+func (m *mission) status() *pb.StatusResponse {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	status := "waiting"
+	if m.isWorking {
+		status = "working"
+	}
+	if m.missionSuccess {
+		status = "success"
+	}
+	if m.missionFailed {
+		status = "failed"
+	}
+	if m.aborted {
+		status = "aborted"
+	}
+
+	return &pb.StatusResponse{
+		Status:         status,
+		TurnsCompleted: m.currentTurns,
+		TotalTurns:     m.totalTurns,
+		CurrentStars:   m.currentStars,
+		ExtraLoot:      m.extraLoot,
+		MissionId:      m.id,
+		Abilities:      append([]string(nil), m.triggered...),
+	}
+}
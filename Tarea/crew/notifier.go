@@ -0,0 +1,39 @@
+package crew
+
+import "sync"
+
+// statusNotifier avisa a los WatchStatus activos de cada cambio de estado.
+type statusNotifier struct {
+	mu       sync.Mutex
+	watchers map[chan struct{}]struct{}
+}
+
+func (n *statusNotifier) subscribe() chan struct{} {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.watchers == nil {
+		n.watchers = make(map[chan struct{}]struct{})
+	}
+	ch := make(chan struct{}, 1)
+	n.watchers[ch] = struct{}{}
+	return ch
+}
+
+func (n *statusNotifier) unsubscribe(ch chan struct{}) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	delete(n.watchers, ch)
+}
+
+func (n *statusNotifier) notify() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for ch := range n.watchers {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
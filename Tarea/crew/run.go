@@ -0,0 +1,68 @@
+package crew
+
+import (
+	"log"
+	"net"
+	"os"
+
+	"Tarea/ability"
+	"Tarea/config"
+	pb "Tarea/proto"
+	"Tarea/starbus"
+
+	"google.golang.org/grpc"
+)
+
+// Run carga la configuración de service y sirve a profile por gRPC hasta que
+// el servidor termine. Es el main de cada binario del equipo.
+func Run(service string, profile Profile) {
+	cfg, err := config.Load(service, os.Args[1:])
+	if err != nil {
+		log.Fatalf("Error cargando configuración: %v", err)
+	}
+
+	endpoint := cfg.Endpoint(service)
+	if endpoint == nil {
+		log.Fatalf("No hay endpoint configurado para %s", service)
+	}
+
+	// Las habilidades configuradas para el servicio reemplazan a las del perfil
+	names := profile.Abilities
+	if configured, ok := cfg.CrewAbilities[service]; ok {
+		names = configured
+	}
+	abilities, err := ability.Builtin().Merge(cfg.Abilities).Resolve(names)
+	if err != nil {
+		log.Fatalf("Error cargando habilidades de %s: %v", profile.Name, err)
+	}
+
+	// Los pagos se verifican contra el libro que aloja Lester
+	lesterConn, err := grpc.Dial(cfg.Lester.Address, grpc.WithInsecure())
+	if err != nil {
+		log.Fatalf("No se pudo conectar a Lester: %v", err)
+	}
+	defer lesterConn.Close()
+
+	// Con -bus grpc las estrellas llegan por la misma conexión con Lester
+	var starBus starbus.StarBus = starbus.NewGRPC(lesterConn)
+	if cfg.StarBus != starbus.KindGRPC {
+		starBus, err = starbus.New(cfg.StarBus, cfg.AMQPURL)
+		if err != nil {
+			log.Fatalf("Error conectando al bus de estrellas: %v", err)
+		}
+	}
+	defer starBus.Close()
+
+	lis, err := net.Listen("tcp", endpoint.Listen)
+	if err != nil {
+		log.Fatalf("Error al escuchar: %v", err)
+	}
+
+	grpcServer := grpc.NewServer()
+	pb.RegisterMissionServiceServer(grpcServer, NewCrewMember(profile, abilities, starBus, pb.NewLedgerServiceClient(lesterConn), cfg.Split, cfg.TurnDuration, cfg.Seed))
+
+	log.Printf("Servidor de %s escuchando en %s (habilidades: %v)", profile.Name, endpoint.Listen, names)
+	if err := grpcServer.Serve(lis); err != nil {
+		log.Fatalf("Error en gRPC: %v", err)
+	}
+}
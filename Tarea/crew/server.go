@@ -0,0 +1,477 @@
+package crew
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"Tarea/ability"
+	"Tarea/dedup"
+	"Tarea/ledger"
+	pb "Tarea/proto"
+	"Tarea/seed"
+	"Tarea/split"
+	"Tarea/starbus"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// missionRetention es cuánto se conserva una misión terminada, para que
+// Michael consulte su estado y su botín final, antes de descartarla.
+const missionRetention = 10 * time.Minute
+
+// CrewMember implementa MissionService para un miembro del equipo.
+type CrewMember struct {
+	pb.UnimplementedMissionServiceServer
+	profile   Profile
+	abilities []ability.Ability
+	starBus   starbus.StarBus
+	ledger    pb.LedgerServiceClient // libro de pagos, para verificar los pagos
+	payments  *dedup.Store           // respuestas de los pagos ya recibidos
+	splits    split.Params           // para recalcular los repartos
+	turn      time.Duration
+	seed      int64 // semilla propia para las misiones que no traen una
+
+	mu       sync.Mutex
+	missions map[string]*mission
+}
+
+// NewCrewMember crea el servidor de profile con las habilidades ya resueltas;
+// profile.Abilities solo se usa para resolverlas (ver Run).
+func NewCrewMember(profile Profile, abilities []ability.Ability, starBus starbus.StarBus,
+	ledger pb.LedgerServiceClient, splits split.Params, turn time.Duration, seed int64) *CrewMember {
+	return &CrewMember{
+		profile:   profile,
+		abilities: abilities,
+		starBus:   starBus,
+		ledger:    ledger,
+		payments:  dedup.NewStore(),
+		splits:    splits,
+		turn:      turn,
+		seed:      seed,
+		missions:  make(map[string]*mission),
+	}
+}
+
+// newMission registra una misión nueva, reemplazando cualquier estado previo
+// con el mismo ID. phaseSeed es la semilla que trae el pedido; si es 0 se
+// deriva de la semilla del miembro.
+//
+// La semilla identifica el intento: un pedido repetido con la misma fase y
+// semilla (un reintento de Michael) devuelve la misión ya iniciada con
+// started en false, en lugar de reiniciarla.
+func (s *CrewMember) newMission(id, kind string, phaseSeed int64) (m *mission, started bool) {
+	var rng *rand.Rand
+	if phaseSeed != 0 {
+		rng = seed.New(phaseSeed)
+	} else {
+		rng = seed.New(s.seed, s.profile.Name, id, kind)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if prev, exists := s.missions[id]; exists {
+		if phaseSeed != 0 && prev.kind == kind && prev.seed == phaseSeed {
+			return prev, false
+		}
+		prev.cancel()
+	}
+	// Un servidor de larga vida recibe miles de misiones; las terminadas se
+	// descartan al llegar otras nuevas
+	now := time.Now()
+	for other, prev := range s.missions {
+		if prev.expired(now, missionRetention) {
+			delete(s.missions, other)
+		}
+	}
+
+	m = newMission(id, s.turn, len(s.abilities), rng)
+	m.kind, m.seed = kind, phaseSeed
+	s.missions[id] = m
+	return m, true
+}
+
+func (s *CrewMember) getMission(id string) (*mission, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, ok := s.missions[id]
+	return m, ok
+}
+
+func (s *CrewMember) StartDistraction(ctx context.Context, req *pb.DistractionRequest) (*pb.DistractionResponse, error) {
+	m, started := s.newMission(req.MissionId, "distraction", req.Seed)
+	if !started {
+		log.Printf("[%s] %s ya había comenzado esta distracción", m.id, s.profile.Name)
+		return &pb.DistractionResponse{
+			Success: true,
+			Message: s.profile.Name + " ya comenzó la distracción",
+		}, nil
+	}
+	m.mu.Lock()
+	m.totalTurns = req.RequiredTurns
+	m.isWorking = true
+	m.mu.Unlock()
+
+	m.notifier.notify()
+
+	log.Printf("[%s] %s iniciando distracción. Turnos requeridos: %d", m.id, s.profile.Name, req.RequiredTurns)
+	go s.workOnDistraction(m)
+
+	return &pb.DistractionResponse{
+		Success: true,
+		Message: s.profile.Name + " comenzó la distracción",
+	}, nil
+}
+
+func (s *CrewMember) StartGolpe(ctx context.Context, req *pb.GolpeRequest) (*pb.GolpeResponse, error) {
+	m, started := s.newMission(req.MissionId, "golpe", req.Seed)
+	if !started {
+		log.Printf("[%s] %s ya había comenzado este golpe", m.id, s.profile.Name)
+		return &pb.GolpeResponse{
+			Success:        true,
+			Message:        s.profile.Name + " ya comenzó el golpe",
+			TurnDurationUs: s.turn.Microseconds(),
+		}, nil
+	}
+	m.mu.Lock()
+	m.totalTurns = req.RequiredTurns
+	m.isWorking = true
+	m.baseLoot = req.BaseLoot
+	m.finalLoot = req.BaseLoot
+	m.mu.Unlock()
+
+	m.notifier.notify()
+
+	log.Printf("[%s] %s iniciando golpe. Turnos requeridos: %d, Botín base: $%d",
+		m.id, s.profile.Name, req.RequiredTurns, req.BaseLoot)
+
+	// Consumir estrellas del bus
+	go s.consumeStars(m)
+	go s.workOnGolpe(m)
+
+	return &pb.GolpeResponse{
+		Success:        true,
+		Message:        s.profile.Name + " comenzó el golpe",
+		TurnDurationUs: s.turn.Microseconds(),
+	}, nil
+}
+
+func (s *CrewMember) consumeStars(m *mission) {
+	// La suscripción se cierra al cancelarse la misión
+	stars, err := s.starBus.Subscribe(m.ctx, s.profile.Name, m.id)
+	if err != nil {
+		log.Printf("[%s] Error suscribiendo a estrellas: %v", m.id, err)
+		return
+	}
+
+	for star := range stars {
+		m.mu.Lock()
+		if star.Turn > m.currentTurns {
+			// Se aplica en workOnGolpe al llegar a su turno
+			m.pending = append(m.pending, star)
+			m.mu.Unlock()
+			continue
+		}
+		if star.Turn > 0 {
+			log.Printf("[%s] La estrella %d de %s llegó tarde (turno %d, va en el %d); la corrida no es reproducible",
+				m.id, star.Count, s.profile.Name, star.Turn, m.currentTurns)
+		}
+		m.setStars(star.Count)
+		log.Printf("[%s] %s - Estrellas actualizadas: %d", m.id, s.profile.Name, m.currentStars)
+
+		failed := s.evaluate(m)
+		m.mu.Unlock()
+		m.notifier.notify()
+
+		if failed {
+			break
+		}
+	}
+}
+
+// evaluate activa las habilidades cuyo disparador se cumple y verifica si las
+// estrellas hacen fracasar el golpe. Requiere m.mu tomado; devuelve true si la
+// misión acaba de fracasar.
+func (s *CrewMember) evaluate(m *mission) bool {
+	state := ability.State{
+		Stars:      m.currentStars,
+		Turn:       m.currentTurns,
+		TotalTurns: m.totalTurns,
+		Rand:       m.rng,
+	}
+	for i, a := range s.abilities {
+		if m.activated[i] || !a.Triggered(state) {
+			continue
+		}
+		message := a.Activate(&m.effects)
+		log.Println(message)
+		m.activated[i] = true
+		m.triggered = append(m.triggered, strings.TrimSpace(message))
+		// Una reducción de estrellas nueva se aplica también a las actuales
+		m.setStars(m.rawStars)
+	}
+
+	// Verificar fracaso - el límite depende de las habilidades activas
+	if m.currentStars >= m.effects.MaxStars && !m.finished() {
+		log.Printf(" Demasiadas estrellas (%d)! Misión fracasada", m.currentStars)
+		m.missionFailed = true
+		return true
+	}
+	return false
+}
+
+// applyPendingStars aplica las estrellas retenidas cuyo turno ya llegó.
+// Requiere m.mu tomado.
+func (s *CrewMember) applyPendingStars(m *mission) {
+	remaining := m.pending[:0]
+	for _, star := range m.pending {
+		if star.Turn > m.currentTurns {
+			remaining = append(remaining, star)
+			continue
+		}
+		m.setStars(star.Count)
+		log.Printf("[%s] %s - Estrellas actualizadas: %d (turno %d)", m.id, s.profile.Name, m.currentStars, m.currentTurns)
+	}
+	m.pending = remaining
+}
+
+func (s *CrewMember) workOnDistraction(m *mission) {
+	defer m.cancel()
+
+	for {
+		if !m.sleepTurn() {
+			return
+		}
+
+		m.mu.Lock()
+		if m.finished() || m.currentTurns >= m.totalTurns {
+			m.mu.Unlock()
+			break
+		}
+		m.currentTurns++
+
+		failed := false
+		if s.profile.Mishap != nil {
+			var message string
+			if message, failed = s.profile.Mishap(m.rng, m.currentTurns, m.totalTurns); failed {
+				log.Println(message)
+				m.missionFailed = true
+			}
+		}
+		m.mu.Unlock()
+		m.notifier.notify()
+
+		if failed {
+			return
+		}
+	}
+
+	m.mu.Lock()
+	if !m.finished() {
+		m.missionSuccess = true
+		log.Printf("[%s] %s completó la distracción con éxito!", m.id, s.profile.Name)
+	}
+	m.mu.Unlock()
+	m.notifier.notify()
+}
+
+func (s *CrewMember) workOnGolpe(m *mission) {
+	defer m.cancel()
+
+	for {
+		if !m.sleepTurn() {
+			return
+		}
+
+		m.mu.Lock()
+		if m.finished() || m.currentTurns >= m.totalTurns {
+			m.mu.Unlock()
+			break
+		}
+		advanced := m.effects.TurnsPerTick
+		if remaining := m.totalTurns - m.currentTurns; advanced > remaining {
+			advanced = remaining
+		}
+		m.currentTurns += advanced
+
+		if m.effects.LootPerTurn != 0 {
+			m.extraLoot += m.effects.LootPerTurn * advanced
+			m.finalLoot = m.baseLoot + m.extraLoot
+		}
+		s.applyPendingStars(m)
+		s.evaluate(m)
+		m.mu.Unlock()
+		m.notifier.notify()
+	}
+
+	m.mu.Lock()
+	if !m.finished() {
+		m.missionSuccess = true
+		m.finalLoot = m.baseLoot + m.extraLoot
+		log.Printf("[%s] %s completó el golpe con éxito! Loot extra: $%d, Botín final: $%d",
+			m.id, s.profile.Name, m.extraLoot, m.finalLoot)
+	}
+	m.mu.Unlock()
+	m.notifier.notify()
+}
+
+func (s *CrewMember) GetFinalLoot(ctx context.Context, req *pb.LootRequest) (*pb.LootResponse, error) {
+	if m, ok := s.getMission(req.MissionId); ok {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		if m.missionSuccess {
+			log.Printf("[%s] %s entregando botín final: $%d", m.id, s.profile.Name, m.finalLoot)
+			return &pb.LootResponse{FinalLoot: m.finalLoot}, nil
+		}
+	}
+	return nil, fmt.Errorf("la misión %s de %s no ha sido completada con éxito", req.MissionId, s.profile.Name)
+}
+
+func (s *CrewMember) CheckStatus(ctx context.Context, req *pb.StatusRequest) (*pb.StatusResponse, error) {
+	m, ok := s.getMission(req.MissionId)
+	if !ok {
+		return &pb.StatusResponse{Status: "waiting"}, nil
+	}
+	return m.status(), nil
+}
+
+// WatchStatus envía el estado inicial y luego cada cambio hasta que la misión
+// termina o el cliente cancela.
+func (s *CrewMember) WatchStatus(req *pb.StatusRequest, stream pb.MissionService_WatchStatusServer) error {
+	m, ok := s.getMission(req.MissionId)
+	if !ok {
+		return status.Errorf(codes.NotFound, "misión %s desconocida", req.MissionId)
+	}
+
+	updates := m.notifier.subscribe()
+	defer m.notifier.unsubscribe(updates)
+
+	var last *pb.StatusResponse
+	for {
+		current := m.status()
+		if last == nil || !proto.Equal(current, last) {
+			if err := stream.Send(current); err != nil {
+				return err
+			}
+			last = current
+		}
+
+		if current.Status == "success" || current.Status == "failed" || current.Status == "aborted" {
+			return nil
+		}
+
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case <-updates:
+		}
+	}
+}
+
+// AbortMission cancela la misión en curso: detiene el worker y el consumidor
+// de estrellas y deja la misión en estado "aborted".
+func (s *CrewMember) AbortMission(ctx context.Context, req *pb.AbortRequest) (*pb.AbortResponse, error) {
+	m, ok := s.getMission(req.MissionId)
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "misión %s desconocida", req.MissionId)
+	}
+
+	m.mu.Lock()
+	alreadyFinished := m.finished()
+	if !alreadyFinished {
+		m.aborted = true
+	}
+	m.mu.Unlock()
+
+	m.cancel()
+	m.notifier.notify()
+
+	if alreadyFinished {
+		log.Printf("[%s] Aborto ignorado: la misión de %s ya había terminado", m.id, s.profile.Name)
+		return &pb.AbortResponse{
+			Success: false,
+			Message: "La misión ya había terminado",
+		}, nil
+	}
+
+	log.Printf("[%s] %s abortó la misión: %s", m.id, s.profile.Name, req.Reason)
+	return &pb.AbortResponse{
+		Success: true,
+		Message: s.profile.Name + " abandonó la misión",
+	}, nil
+}
+
+// ReceivePayment es idempotente por payment_id: un reintento recibe la
+// respuesta del primer pago.
+func (s *CrewMember) ReceivePayment(ctx context.Context, req *pb.PaymentRequest) (*pb.PaymentResponse, error) {
+	resp, replayed, err := s.payments.Do(dedup.Key(req), func() (*pb.PaymentResponse, error) {
+		return s.receivePayment(ctx, req)
+	})
+	if replayed {
+		log.Printf("[%s] %s ya había recibido el pago %s", req.MissionId, s.profile.Name, req.PaymentId)
+	}
+	return resp, err
+}
+
+func (s *CrewMember) receivePayment(ctx context.Context, req *pb.PaymentRequest) (*pb.PaymentResponse, error) {
+	log.Printf("[%s] %s recibió pago de $%d", req.MissionId, s.profile.Name, req.Amount)
+
+	// El pago se verifica contra el libro de Lester; si no responde, al
+	// menos contra el reparto que viene con el pago. Si así tampoco se
+	// confirma se devuelve un error, que no queda registrado para el
+	// payment_id: el reintento vuelve a consultar el libro
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	var expected int32
+	resp, err := s.ledger.ListTransactions(ctx, &pb.TransactionsRequest{
+		Party:     s.profile.Name,
+		MissionId: req.MissionId,
+	})
+	if err != nil {
+		ledgerErr := err
+		if req.Split == nil {
+			log.Printf("[%s] %s no pudo consultar el libro: %v", req.MissionId, s.profile.Name, ledgerErr)
+			return nil, status.Errorf(codes.Unavailable, "no se pudo consultar el libro: %v", ledgerErr)
+		}
+		log.Printf("[%s] %s no pudo consultar el libro (%v); se verifica con el reparto recibido", req.MissionId, s.profile.Name, ledgerErr)
+		if expected, err = ledger.VerifyShare(req.Split, s.profile.Name, req.Amount, s.splits); err != nil {
+			log.Printf("[%s] %s no pudo verificar el pago sin el libro: %v", req.MissionId, s.profile.Name, err)
+			return nil, status.Errorf(codes.Unavailable, "no se pudo consultar el libro: %v", ledgerErr)
+		}
+	} else {
+		expected, err = ledger.Verify(resp, s.profile.Name, req, s.splits)
+	}
+	if err != nil {
+		log.Printf("[%s] %s no pudo verificar el pago: %v", req.MissionId, s.profile.Name, err)
+		return &pb.PaymentResponse{
+			Message:        fmt.Sprintf("Error: %v", err),
+			CorrectAmount:  false,
+			ExpectedAmount: expected,
+			Difference:     difference(req.Amount, expected),
+		}, nil
+	}
+
+	return &pb.PaymentResponse{
+		Message:        s.profile.PaymentMessage,
+		CorrectAmount:  true,
+		ExpectedAmount: expected,
+	}, nil
+}
+
+// difference es lo recibido menos lo esperado, o 0 si no se sabe qué se
+// esperaba.
+func difference(amount, expected int32) int32 {
+	if expected == 0 {
+		return 0
+	}
+	return amount - expected
+}
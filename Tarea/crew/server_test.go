@@ -0,0 +1,310 @@
+package crew
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"Tarea/ability"
+	pb "Tarea/proto"
+	"Tarea/split"
+	"Tarea/starbus"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+)
+
+// newTestCrew crea a Franklin con Chop sobre un MemoryBus y lo sirve por
+// gRPC en memoria, para ejercitar también WatchStatus.
+func newTestCrew(t *testing.T) (*CrewMember, *starbus.MemoryBus, pb.MissionServiceClient) {
+	t.Helper()
+
+	abilities, err := ability.Builtin().Resolve([]string{"chop"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bus := starbus.NewMemory()
+	member := NewCrewMember(Profile{Name: "Franklin"}, abilities, bus, nil, split.DefaultParams(), time.Millisecond, 1)
+
+	lis := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	pb.RegisterMissionServiceServer(server, member)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return member, bus, pb.NewMissionServiceClient(conn)
+}
+
+func finalStatus(status string) bool {
+	return status == "success" || status == "failed" || status == "aborted"
+}
+
+// watch sigue la misión hasta que termina y devuelve el último estado.
+func watch(ctx context.Context, client pb.MissionServiceClient, missionID string) (*pb.StatusResponse, error) {
+	stream, err := client.WatchStatus(ctx, &pb.StatusRequest{MissionId: missionID})
+	if err != nil {
+		return nil, err
+	}
+	var last *pb.StatusResponse
+	for {
+		status, err := stream.Recv()
+		if err == io.EOF {
+			return last, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		last = status
+	}
+}
+
+func TestConcurrentStatus(t *testing.T) {
+	member, bus, client := newTestCrew(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := member.StartDistraction(ctx, &pb.DistractionRequest{RequiredTurns: 100, MissionId: "d"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = member.StartGolpe(ctx, &pb.GolpeRequest{RequiredTurns: 150, PoliceRisk: 50, BaseLoot: 1000, MissionId: "g"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+
+	// Lester: estrellas para el golpe mientras se consulta el estado; con 3
+	// se activa Chop
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for stars := int32(1); stars <= 3; stars++ {
+			time.Sleep(10 * time.Millisecond)
+			if err := bus.Publish("Franklin", "g", starbus.Star{Count: stars}); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+
+	finals := make(chan *pb.StatusResponse, 64)
+	for i := 0; i < 20; i++ {
+		for _, id := range []string{"d", "g"} {
+			wg.Add(1)
+			go func(id string) {
+				defer wg.Done()
+				for ctx.Err() == nil {
+					status, err := member.CheckStatus(ctx, &pb.StatusRequest{MissionId: id})
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					if finalStatus(status.Status) {
+						return
+					}
+				}
+			}(id)
+		}
+	}
+	for i := 0; i < 10; i++ {
+		for _, id := range []string{"d", "g"} {
+			wg.Add(1)
+			go func(id string) {
+				defer wg.Done()
+				status, err := watch(ctx, client, id)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				finals <- status
+			}(id)
+		}
+	}
+	wg.Wait()
+	close(finals)
+
+	for status := range finals {
+		if !finalStatus(status.Status) {
+			t.Errorf("WatchStatus de %s terminó en %q", status.MissionId, status.Status)
+		}
+	}
+
+	distraction, _ := member.CheckStatus(ctx, &pb.StatusRequest{MissionId: "d"})
+	if distraction.Status != "success" || distraction.TurnsCompleted != 100 {
+		t.Errorf("distracción: %s con %d turnos, quería success con 100", distraction.Status, distraction.TurnsCompleted)
+	}
+	golpe, _ := member.CheckStatus(ctx, &pb.StatusRequest{MissionId: "g"})
+	if golpe.Status != "success" || golpe.CurrentStars != 3 || golpe.ExtraLoot == 0 {
+		t.Errorf("golpe: %s con %d estrellas y +$%d, quería success con 3 y Chop activo",
+			golpe.Status, golpe.CurrentStars, golpe.ExtraLoot)
+	}
+}
+
+func TestConcurrentAbort(t *testing.T) {
+	member, bus, client := newTestCrew(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := member.StartGolpe(ctx, &pb.GolpeRequest{RequiredTurns: 100000, PoliceRisk: 50, BaseLoot: 1000, MissionId: "g"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	aborted := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			resp, err := client.AbortMission(ctx, &pb.AbortRequest{MissionId: "g", Reason: "prueba"})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if resp.Success {
+				mu.Lock()
+				aborted++
+				mu.Unlock()
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if _, err := member.CheckStatus(ctx, &pb.StatusRequest{MissionId: "g"}); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func(stars int32) {
+			defer wg.Done()
+			bus.Publish("Franklin", "g", starbus.Star{Count: stars % 4})
+		}(int32(i))
+	}
+	wg.Wait()
+
+	if aborted != 1 {
+		t.Errorf("%d abortos exitosos, quería exactamente 1", aborted)
+	}
+	status, err := watch(ctx, client, "g")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Status != "aborted" {
+		t.Errorf("estado final %q, quería aborted", status.Status)
+	}
+}
+
+// runSeeded corre una distracción y un golpe con la semilla dada en un
+// Franklin nuevo, con estrellas programadas por turno, y devuelve los estados
+// finales.
+func runSeeded(t *testing.T, phaseSeed int64) []*pb.StatusResponse {
+	t.Helper()
+
+	// Una habilidad con chance y un imprevisto al 50% consumen el azar de la
+	// misión
+	abilities, err := ability.Builtin().Merge(map[string]ability.Spec{
+		"suerte": {Trigger: ability.Trigger{Chance: 5}, Effect: ability.Effect{LootPerTurn: 100}},
+	}).Resolve([]string{"chop", "suerte"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	profile := Profile{Name: "Franklin", Mishap: HalfwayMishap(50, "imprevisto")}
+	bus := starbus.NewMemory()
+	member := NewCrewMember(profile, abilities, bus, nil, split.DefaultParams(), 5*time.Millisecond, 1)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err = member.StartDistraction(ctx, &pb.DistractionRequest{RequiredTurns: 20, MissionId: "d", Seed: phaseSeed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = member.StartGolpe(ctx, &pb.GolpeRequest{RequiredTurns: 100, PoliceRisk: 50, BaseLoot: 1000, MissionId: "g", Seed: phaseSeed})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Las estrellas llegan antes de su turno y se aplican en él
+	time.Sleep(20 * time.Millisecond)
+	for stars := int32(1); stars <= 3; stars++ {
+		if err := bus.Publish("Franklin", "g", starbus.Star{Count: stars, Turn: 40 + 10*stars}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var finals []*pb.StatusResponse
+	for _, id := range []string{"d", "g"} {
+		for {
+			status, err := member.CheckStatus(ctx, &pb.StatusRequest{MissionId: id})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if finalStatus(status.Status) {
+				finals = append(finals, status)
+				break
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+	return finals
+}
+
+// TestSeededRunRepeats corre dos veces las mismas misiones con la misma
+// semilla: el resultado debe ser idéntico.
+func TestSeededRunRepeats(t *testing.T) {
+	for _, phaseSeed := range []int64{1, 42, 7919} {
+		first := runSeeded(t, phaseSeed)
+		second := runSeeded(t, phaseSeed)
+		for i := range first {
+			if !proto.Equal(first[i], second[i]) {
+				t.Errorf("semilla %d: %v y luego %v", phaseSeed, first[i], second[i])
+			}
+		}
+	}
+}
+
+// flakyLedger es un libro que no responde las primeras down consultas.
+type flakyLedger struct {
+	pb.LedgerServiceClient
+	down  int
+	calls int
+}
+
+func (l *flakyLedger) ListTransactions(ctx context.Context, req *pb.TransactionsRequest, opts ...grpc.CallOption) (*pb.TransactionsResponse, error) {
+	l.calls++
+	if l.calls <= l.down {
+		return nil, status.Error(codes.Unavailable, "libro caído")
+	}
+	return &pb.TransactionsResponse{}, nil
+}
+
+// TestPaymentRetryAfterLedgerFailure reintenta un pago que no se pudo
+// verificar porque el libro no respondía: el reintento vuelve a consultarlo.
+func TestPaymentRetryAfterLedgerFailure(t *testing.T) {
+	book := &flakyLedger{down: 1}
+	member := NewCrewMember(Profile{Name: "Franklin"}, nil, starbus.NewMemory(), book, split.DefaultParams(), time.Millisecond, 1)
+	ctx := context.Background()
+	payment := &pb.PaymentRequest{Amount: 100, MissionId: "m", PaymentId: "Franklin"}
+
+	if _, err := member.ReceivePayment(ctx, payment); status.Code(err) != codes.Unavailable {
+		t.Fatalf("con el libro caído ReceivePayment devolvió %v, quería Unavailable", err)
+	}
+	if _, err := member.ReceivePayment(ctx, payment); err != nil {
+		t.Fatal(err)
+	}
+	if book.calls != 2 {
+		t.Errorf("el libro se consultó %d veces, quería 2", book.calls)
+	}
+}
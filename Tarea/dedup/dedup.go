@@ -0,0 +1,90 @@
+// Package dedup hace idempotentes los pagos: recuerda la respuesta de cada
+// pago por su ID y la devuelve otra vez si el pago se reintenta.
+package dedup
+
+import (
+	"sync"
+
+	pb "Tarea/proto"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Key identifica un pago; vacío si el pedido no trae payment_id, y entonces
+// no se deduplica.
+func Key(req *pb.PaymentRequest) string {
+	if req.PaymentId == "" {
+		return ""
+	}
+	return req.MissionId + "/" + req.PaymentId
+}
+
+type entry struct {
+	done chan struct{} // se cierra cuando resp está lista
+	resp *pb.PaymentResponse
+}
+
+// Store guarda las respuestas de los pagos procesados. Es seguro para uso
+// concurrente.
+type Store struct {
+	mu      sync.Mutex
+	entries map[string]*entry
+}
+
+// NewStore crea un store vacío.
+func NewStore() *Store {
+	return &Store{entries: make(map[string]*entry)}
+}
+
+// Put guarda la respuesta de un pago ya procesado, por ejemplo al recuperar
+// el estado.
+func (s *Store) Put(key string, resp *pb.PaymentResponse) {
+	e := &entry{done: make(chan struct{}), resp: resp}
+	close(e.done)
+
+	s.mu.Lock()
+	s.entries[key] = e
+	s.mu.Unlock()
+}
+
+// Do procesa el pago key con handle una sola vez. Un reintento recibe la
+// respuesta original, con replayed en true; si llega mientras el primero
+// todavía se procesa, lo espera. Un error no se recuerda, así el pago puede
+// reintentarse. Con key vacío siempre llama a handle.
+func (s *Store) Do(key string, handle func() (*pb.PaymentResponse, error)) (resp *pb.PaymentResponse, replayed bool, err error) {
+	if key == "" {
+		resp, err = handle()
+		return resp, false, err
+	}
+
+	var e *entry
+	for {
+		s.mu.Lock()
+		prev, ok := s.entries[key]
+		if !ok {
+			e = &entry{done: make(chan struct{})}
+			s.entries[key] = e
+			s.mu.Unlock()
+			break
+		}
+		s.mu.Unlock()
+
+		<-prev.done
+		if prev.resp != nil {
+			return proto.Clone(prev.resp).(*pb.PaymentResponse), true, nil
+		}
+		// El intento anterior falló; se vuelve a probar
+	}
+
+	resp, err = handle()
+
+	s.mu.Lock()
+	if err != nil {
+		delete(s.entries, key)
+	} else {
+		e.resp = proto.Clone(resp).(*pb.PaymentResponse)
+	}
+	s.mu.Unlock()
+	close(e.done)
+	return resp, false, err
+}
@@ -0,0 +1,113 @@
+// Package evaluator decide si Michael acepta una oferta de Lester.
+package evaluator
+
+import (
+	"fmt"
+
+	"Tarea/offers"
+)
+
+// OfferEvaluator decide sobre una oferta y explica por qué, para el log.
+type OfferEvaluator interface {
+	Accept(offer offers.Offer) (bool, string)
+}
+
+// Params configura las estrategias incorporadas. Cada estrategia usa solo
+// los campos que le corresponden.
+type Params struct {
+	MinSuccess       int32   `yaml:"min_success"`        // threshold
+	MaxRisk          int32   `yaml:"max_risk"`           // threshold, learn-from-history
+	MinExpectedValue float64 `yaml:"min_expected_value"` // expected-value
+	SafeRisk         int32   `yaml:"safe_risk"`          // risk-averse
+	SafeSuccess      int32   `yaml:"safe_success"`       // risk-averse
+	MinProbability   float64 `yaml:"min_probability"`    // learn-from-history
+}
+
+// DefaultParams reproduce la regla histórica de Michael para threshold.
+func DefaultParams() Params {
+	return Params{
+		MinSuccess:       50,
+		MaxRisk:          80,
+		MinExpectedValue: 250000,
+		SafeRisk:         45,
+		SafeSuccess:      60,
+		MinProbability:   0.5,
+	}
+}
+
+// Names son las estrategias incorporadas, en el orden en que se comparan.
+var Names = []string{"threshold", "expected-value", "risk-averse", "learn-from-history"}
+
+// New devuelve la estrategia con el nombre dado. "" equivale a "threshold".
+// history solo lo usa learn-from-history y puede ser nil.
+func New(name string, params Params, history *History) (OfferEvaluator, error) {
+	switch name {
+	case "threshold", "":
+		return threshold{params}, nil
+	case "expected-value":
+		return expectedValue{params}, nil
+	case "risk-averse":
+		return riskAverse{params}, nil
+	case "learn-from-history":
+		return learner{params, history}, nil
+	default:
+		return nil, fmt.Errorf("evaluador de ofertas desconocido %q", name)
+	}
+}
+
+func bestSuccess(offer offers.Offer) int32 {
+	if offer.SuccessTrevor > offer.SuccessFranklin {
+		return offer.SuccessTrevor
+	}
+	return offer.SuccessFranklin
+}
+
+// threshold es la regla de siempre: algún miembro supera MinSuccess y el
+// riesgo está bajo MaxRisk.
+type threshold struct{ p Params }
+
+func (t threshold) Accept(offer offers.Offer) (bool, string) {
+	ok := bestSuccess(offer) > t.p.MinSuccess && offer.PoliceRisk < t.p.MaxRisk
+	return ok, fmt.Sprintf("mejor exito %d%% (minimo %d%%), riesgo %d%% (maximo %d%%)",
+		bestSuccess(offer), t.p.MinSuccess, offer.PoliceRisk, t.p.MaxRisk)
+}
+
+// expectedValue acepta si el valor esperado (ver offers.ExpectedValue)
+// alcanza MinExpectedValue.
+type expectedValue struct{ p Params }
+
+func (e expectedValue) Accept(offer offers.Offer) (bool, string) {
+	value := offers.ExpectedValue(offer)
+	return value >= e.p.MinExpectedValue, fmt.Sprintf("valor esperado $%.0f (minimo $%.0f)", value, e.p.MinExpectedValue)
+}
+
+// riskAverse solo toma trabajos tranquilos y con un miembro muy confiable.
+type riskAverse struct{ p Params }
+
+func (r riskAverse) Accept(offer offers.Offer) (bool, string) {
+	ok := offer.PoliceRisk <= r.p.SafeRisk && bestSuccess(offer) >= r.p.SafeSuccess
+	return ok, fmt.Sprintf("riesgo %d%% (maximo %d%%), mejor exito %d%% (minimo %d%%)",
+		offer.PoliceRisk, r.p.SafeRisk, bestSuccess(offer), r.p.SafeSuccess)
+}
+
+// learner estima la probabilidad real de éxito con los resultados de
+// misiones anteriores de riesgo parecido. Sin historial confía en la tasa
+// de éxito que declara Lester.
+type learner struct {
+	p       Params
+	history *History
+}
+
+// priorWeight es cuántas misiones vale la tasa declarada por Lester frente
+// a los resultados observados.
+const priorWeight = 2
+
+func (l learner) Accept(offer offers.Offer) (bool, string) {
+	prior := float64(bestSuccess(offer)) / 100
+	successes, total := l.history.Similar(offer)
+	estimate := (float64(successes) + prior*priorWeight) / (float64(total) + priorWeight)
+
+	ok := estimate >= l.p.MinProbability && offer.PoliceRisk < l.p.MaxRisk
+	return ok, fmt.Sprintf("exito estimado %.0f%% (%d/%d misiones parecidas, minimo %.0f%%), riesgo %d%%",
+		estimate*100, successes, total, l.p.MinProbability*100, offer.PoliceRisk)
+}
@@ -0,0 +1,115 @@
+package evaluator
+
+import (
+	"path/filepath"
+	"testing"
+
+	"Tarea/offers"
+)
+
+// loadOffers lee las primeras filas de ofertas.csv y comprueba que sean las
+// que esperan los casos.
+func loadOffers(t *testing.T) []offers.Offer {
+	t.Helper()
+
+	source, err := offers.NewCSV("../ofertas.csv", offers.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer source.Close()
+
+	want := []offers.Offer{
+		{Loot: 1757257, SuccessFranklin: 41, SuccessTrevor: 43, PoliceRisk: 87},
+		{Loot: 1070161, SuccessFranklin: 49, SuccessTrevor: 57, PoliceRisk: 63},
+		{Loot: 269050, SuccessFranklin: 33, SuccessTrevor: 64, PoliceRisk: 23},
+		{Loot: 458687, SuccessFranklin: 67, SuccessTrevor: 87, PoliceRisk: 42},
+		{Loot: 1141047, SuccessFranklin: 74, SuccessTrevor: 81, PoliceRisk: 51},
+	}
+	got := source.Offers()
+	if len(got) < len(want) {
+		t.Fatalf("ofertas.csv tiene %d ofertas válidas, se esperaban al menos %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("oferta %d = %+v, se esperaba %+v", i+1, got[i], want[i])
+		}
+	}
+	return got[:len(want)]
+}
+
+func TestStrategies(t *testing.T) {
+	rows := loadOffers(t)
+
+	// Decisión de cada estrategia, con DefaultParams y sin historial, para
+	// las filas 1 a 5 de ofertas.csv
+	tests := map[string][]bool{
+		"threshold":          {false, true, true, true, true},
+		"expected-value":     {false, false, false, false, true},
+		"risk-averse":        {false, false, true, true, false},
+		"learn-from-history": {false, true, true, true, true},
+	}
+	for _, name := range Names {
+		want, ok := tests[name]
+		if !ok {
+			t.Errorf("falta el caso de %s", name)
+			continue
+		}
+		strategy, err := New(name, DefaultParams(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, offer := range rows {
+			if got, reason := strategy.Accept(offer); got != want[i] {
+				t.Errorf("%s, fila %d: Accept = %v (%s), quería %v", name, i+1, got, reason, want[i])
+			}
+		}
+	}
+}
+
+func TestLearnFromHistory(t *testing.T) {
+	rows := loadOffers(t)
+	path := filepath.Join(t.TempDir(), "historial.jsonl")
+
+	history, err := OpenHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Las misiones de riesgo 60-79 fracasaron y las de 20-39 salieron bien
+	for i := 0; i < 4; i++ {
+		if err := history.Record(offers.Offer{Loot: 1, SuccessTrevor: 90, PoliceRisk: 70}, false); err != nil {
+			t.Fatal(err)
+		}
+		if err := history.Record(offers.Offer{Loot: 1, SuccessTrevor: 10, PoliceRisk: 30}, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	history.Close()
+
+	// El historial se recupera del journal
+	history, err = OpenHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer history.Close()
+	if history.Len() != 8 {
+		t.Fatalf("historial con %d misiones, quería 8", history.Len())
+	}
+
+	learner, err := New("learn-from-history", DefaultParams(), history)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		row  int
+		want bool
+	}{
+		{2, false}, // riesgo 63: (0 + 0.57×2) / (4 + 2) = 19%
+		{3, true},  // riesgo 23: (4 + 0.64×2) / (4 + 2) = 88%
+		{4, true},  // riesgo 42: sin misiones parecidas, vale el 87% declarado
+	}
+	for _, tt := range tests {
+		if got, reason := learner.Accept(rows[tt.row-1]); got != tt.want {
+			t.Errorf("fila %d: Accept = %v (%s), quería %v", tt.row, got, reason, tt.want)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package evaluator
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"Tarea/journal"
+	"Tarea/offers"
+)
+
+// riskBucket agrupa las misiones por riesgo policial en tramos de este ancho.
+const riskBucket = 20
+
+// Outcome es el resultado de una misión aceptada.
+type Outcome struct {
+	Offer   offers.Offer `json:"offer"`
+	Success bool         `json:"success"`
+	Time    time.Time    `json:"time"`
+}
+
+// History guarda el resultado de cada misión en un journal para que
+// learn-from-history aprenda entre ejecuciones de Michael. Un *History nil
+// está vacío y no guarda nada.
+type History struct {
+	mu       sync.Mutex
+	outcomes []Outcome
+	journal  *journal.Journal
+}
+
+// OpenHistory carga el historial de path y lo deja listo para agregar.
+func OpenHistory(path string) (*History, error) {
+	h := &History{}
+	j, err := journal.Open(path, func(raw json.RawMessage) error {
+		var outcome Outcome
+		if err := json.Unmarshal(raw, &outcome); err != nil {
+			return err
+		}
+		h.outcomes = append(h.outcomes, outcome)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	h.journal = j
+	return h, nil
+}
+
+// Len es la cantidad de misiones registradas.
+func (h *History) Len() int {
+	if h == nil {
+		return 0
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.outcomes)
+}
+
+// Record agrega el resultado de una misión.
+func (h *History) Record(offer offers.Offer, success bool) error {
+	if h == nil {
+		return nil
+	}
+	outcome := Outcome{Offer: offer, Success: success, Time: time.Now()}
+
+	h.mu.Lock()
+	h.outcomes = append(h.outcomes, outcome)
+	h.mu.Unlock()
+	return h.journal.Append(outcome)
+}
+
+// Similar cuenta los éxitos y el total de misiones en el mismo tramo de
+// riesgo que offer.
+func (h *History) Similar(offer offers.Offer) (successes, total int) {
+	if h == nil {
+		return 0, 0
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, outcome := range h.outcomes {
+		if outcome.Offer.PoliceRisk/riskBucket != offer.PoliceRisk/riskBucket {
+			continue
+		}
+		total++
+		if outcome.Success {
+			successes++
+		}
+	}
+	return successes, total
+}
+
+func (h *History) Close() error {
+	if h == nil {
+		return nil
+	}
+	return h.journal.Close()
+}
@@ -0,0 +1,160 @@
+// Package journal es un log de solo agregado: un objeto JSON por línea.
+// Al abrirlo se reproducen los registros existentes para reconstruir el
+// estado y después se siguen agregando al final.
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+)
+
+// Journal agrega registros a un archivo. Un *Journal nil descarta los
+// registros, para usarlo cuando la persistencia está desactivada.
+type Journal struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+// Open reproduce los registros de path con replay, en orden, y deja el
+// journal listo para agregar. Si el archivo no existe se crea.
+//
+// Una última línea incompleta (una caída a mitad de escritura) se descarta
+// y se recorta del archivo; un registro inválido en medio del archivo es un
+// error.
+func Open(path string, replay func(json.RawMessage) error) (*Journal, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo abrir el journal: %w", err)
+	}
+
+	valid, err := replayFile(file, replay)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("journal %s: %w", path, err)
+	}
+	if err := file.Truncate(valid); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("no se pudo recortar el journal: %w", err)
+	}
+	if _, err := file.Seek(valid, io.SeekStart); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("no se pudo posicionar el journal: %w", err)
+	}
+
+	return &Journal{path: path, file: file}, nil
+}
+
+// replayFile devuelve cuántos bytes del archivo contienen registros válidos.
+func replayFile(file *os.File, replay func(json.RawMessage) error) (int64, error) {
+	reader := bufio.NewReader(file)
+	var offset int64
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			if len(data) > 0 {
+				log.Printf("Journal: se descarta la línea %d incompleta", line)
+			}
+			return offset, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		if !json.Valid(data) {
+			// Solo se tolera si es la última línea
+			if _, err := reader.Peek(1); err == io.EOF {
+				log.Printf("Journal: se descarta la línea %d inválida", line)
+				return offset, nil
+			}
+			return 0, fmt.Errorf("línea %d inválida", line)
+		}
+		if err := replay(json.RawMessage(data)); err != nil {
+			return 0, fmt.Errorf("línea %d: %w", line, err)
+		}
+		offset += int64(len(data))
+	}
+}
+
+// Append escribe record como una línea y la lleva a disco antes de volver.
+func (j *Journal) Append(record any) error {
+	if j == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if _, err := j.file.Write(data); err != nil {
+		return err
+	}
+	return j.file.Sync()
+}
+
+// Rewrite reemplaza los registros del journal por records, por ejemplo para
+// compactarlo con un resumen del estado. El archivo nuevo se escribe aparte
+// y se renombra, así una caída deja el anterior o el nuevo completo.
+func (j *Journal) Rewrite(records []any) error {
+	if j == nil {
+		return nil
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	tmp := j.path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(file)
+	for _, record := range records {
+		data, err := json.Marshal(record)
+		if err == nil {
+			writer.Write(data)
+			err = writer.WriteByte('\n')
+		}
+		if err != nil {
+			file.Close()
+			os.Remove(tmp)
+			return err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, j.path); err != nil {
+		file.Close()
+		os.Remove(tmp)
+		return err
+	}
+
+	j.file.Close()
+	j.file = file
+	return nil
+}
+
+func (j *Journal) Close() error {
+	if j == nil {
+		return nil
+	}
+	return j.file.Close()
+}
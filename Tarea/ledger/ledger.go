@@ -0,0 +1,329 @@
+// Package ledger es el libro de pagos de las misiones: el reparto acordado de
+// cada una y todas las transferencias con quién paga, quién cobra, la misión
+// y la hora. Lester lo aloja (ver Server) y el equipo lo consulta para
+// verificar sus pagos.
+package ledger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"Tarea/journal"
+	pb "Tarea/proto"
+	"Tarea/split"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Loot es el pagador del depósito inicial: al registrar un reparto, el botín
+// total se acredita a quien lo reparte.
+const Loot = "Botín"
+
+// Errores del libro; Server los traduce a códigos gRPC.
+var (
+	ErrInvalid  = errors.New("movimiento inválido")
+	ErrConflict = errors.New("conflicto con el libro")
+	ErrNoSplit  = errors.New("no hay reparto registrado")
+	ErrOverpaid = errors.New("el pago excede la parte acordada")
+)
+
+// Tipos de evento del journal.
+const (
+	eventSplit    = "split"
+	eventTransfer = "transfer"
+)
+
+type event struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// Ledger guarda repartos y transferencias. Es seguro para uso concurrente.
+type Ledger struct {
+	mu        sync.Mutex
+	journal   *journal.Journal
+	splits    map[string]*pb.Split
+	transfers []*pb.Transfer
+}
+
+// Open abre el libro guardado en path, o uno solo en memoria si path es "".
+func Open(path string) (*Ledger, error) {
+	l := &Ledger{splits: make(map[string]*pb.Split)}
+	if path == "" {
+		return l, nil
+	}
+
+	j, err := journal.Open(path, l.replay)
+	if err != nil {
+		return nil, err
+	}
+	l.journal = j
+	return l, nil
+}
+
+// Close cierra el journal, si hay.
+func (l *Ledger) Close() error {
+	return l.journal.Close()
+}
+
+// Len devuelve cuántos repartos y transferencias hay registrados.
+func (l *Ledger) Len() (splits, transfers int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.splits), len(l.transfers)
+}
+
+// RecordSplit registra el reparto de una misión y acredita el botín total a
+// split.Payer. Registrar otra vez el mismo reparto no hace nada; uno distinto
+// para la misma misión es un conflicto.
+func (l *Ledger) RecordSplit(split *pb.Split) error {
+	if split.MissionId == "" || split.Payer == "" || split.TotalLoot <= 0 {
+		return fmt.Errorf("%w: el reparto necesita misión, pagador y botín", ErrInvalid)
+	}
+	var sum int64
+	for name, amount := range split.Shares {
+		if amount < 0 {
+			return fmt.Errorf("%w: la parte de %s es negativa", ErrInvalid, name)
+		}
+		sum += int64(amount)
+	}
+	if sum > int64(split.TotalLoot) {
+		return fmt.Errorf("%w: las partes suman $%d y el botín es $%d", ErrInvalid, sum, split.TotalLoot)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if prev, ok := l.splits[split.MissionId]; ok {
+		if proto.Equal(prev, split) {
+			return nil
+		}
+		return fmt.Errorf("%w: la misión %s ya tiene otro reparto", ErrConflict, split.MissionId)
+	}
+
+	split = proto.Clone(split).(*pb.Split)
+	if err := l.append(eventSplit, split); err != nil {
+		return err
+	}
+	l.splits[split.MissionId] = split
+
+	deposit := &pb.Transfer{MissionId: split.MissionId, Payer: Loot, Payee: split.Payer, Amount: split.TotalLoot}
+	return l.addTransfer(deposit)
+}
+
+// Transfer registra un pago de la misión y lo devuelve con id y hora. El
+// pagador debe ser quien reparte y el monto no puede pasarse de la parte
+// acordada del cobrador. Si el pago trae PaymentId y ya estaba registrado
+// devuelve la transferencia original.
+func (l *Ledger) Transfer(transfer *pb.Transfer) (*pb.Transfer, error) {
+	if transfer.Payee == "" || transfer.Amount <= 0 {
+		return nil, fmt.Errorf("%w: el pago necesita cobrador y un monto positivo", ErrInvalid)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	split, ok := l.splits[transfer.MissionId]
+	if !ok {
+		return nil, fmt.Errorf("%w para la misión %q", ErrNoSplit, transfer.MissionId)
+	}
+	if transfer.Payer != split.Payer {
+		return nil, fmt.Errorf("%w: en la misión %s paga %s, no %s", ErrInvalid, split.MissionId, split.Payer, transfer.Payer)
+	}
+	if prev := l.find(transfer.MissionId, transfer.PaymentId); prev != nil {
+		if prev.Payer != transfer.Payer || prev.Payee != transfer.Payee || prev.Amount != transfer.Amount {
+			return nil, fmt.Errorf("%w: el pago %s ya se registró con otros datos", ErrConflict, transfer.PaymentId)
+		}
+		return proto.Clone(prev).(*pb.Transfer), nil
+	}
+	share, ok := split.Shares[transfer.Payee]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s no figura en el reparto de la misión %s", ErrInvalid, transfer.Payee, split.MissionId)
+	}
+	if paid := l.paid(split.MissionId, transfer.Payee); paid+int64(transfer.Amount) > int64(share) {
+		return nil, fmt.Errorf("%w: %s ya cobró $%d de $%d", ErrOverpaid, transfer.Payee, paid, share)
+	}
+
+	transfer = &pb.Transfer{
+		MissionId: transfer.MissionId,
+		Payer:     transfer.Payer,
+		Payee:     transfer.Payee,
+		Amount:    transfer.Amount,
+		PaymentId: transfer.PaymentId,
+	}
+	if err := l.addTransfer(transfer); err != nil {
+		return nil, err
+	}
+	return proto.Clone(transfer).(*pb.Transfer), nil
+}
+
+// addTransfer asigna id y hora y agrega la transferencia. Requiere l.mu.
+func (l *Ledger) addTransfer(transfer *pb.Transfer) error {
+	transfer.Id = int64(len(l.transfers) + 1)
+	transfer.TimeUnixMs = time.Now().UnixMilli()
+	if err := l.append(eventTransfer, transfer); err != nil {
+		return err
+	}
+	l.transfers = append(l.transfers, transfer)
+	return nil
+}
+
+// find busca la transferencia paymentID de la misión. Requiere l.mu.
+func (l *Ledger) find(missionID, paymentID string) *pb.Transfer {
+	if paymentID == "" {
+		return nil
+	}
+	for _, t := range l.transfers {
+		if t.MissionId == missionID && t.PaymentId == paymentID {
+			return t
+		}
+	}
+	return nil
+}
+
+// paid suma lo que payee cobró en la misión. Requiere l.mu.
+func (l *Ledger) paid(missionID, payee string) int64 {
+	var paid int64
+	for _, t := range l.transfers {
+		if t.MissionId == missionID && t.Payee == payee && t.Payer != Loot {
+			paid += int64(t.Amount)
+		}
+	}
+	return paid
+}
+
+// Balance devuelve lo que party recibió y pagó en todas las misiones.
+func (l *Ledger) Balance(party string) *pb.BalanceResponse {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	balance := &pb.BalanceResponse{Party: party}
+	for _, t := range l.transfers {
+		if t.Payee == party {
+			balance.Received += int64(t.Amount)
+		}
+		if t.Payer == party {
+			balance.Paid += int64(t.Amount)
+		}
+	}
+	balance.Balance = balance.Received - balance.Paid
+	return balance
+}
+
+// Transactions devuelve las transferencias en las que participa party, de
+// la misión missionID; un filtro vacío no filtra. Si se indica la misión
+// también devuelve su reparto.
+func (l *Ledger) Transactions(party, missionID string) *pb.TransactionsResponse {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	resp := &pb.TransactionsResponse{}
+	for _, t := range l.transfers {
+		if missionID != "" && t.MissionId != missionID {
+			continue
+		}
+		if party != "" && t.Payer != party && t.Payee != party {
+			continue
+		}
+		resp.Transfers = append(resp.Transfers, proto.Clone(t).(*pb.Transfer))
+	}
+	if split, ok := l.splits[missionID]; ok && missionID != "" {
+		resp.Split = proto.Clone(split).(*pb.Split)
+	}
+	return resp
+}
+
+// append guarda el evento en el journal. Requiere l.mu.
+func (l *Ledger) append(kind string, m proto.Message) error {
+	data, err := protojson.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if err := l.journal.Append(event{Type: kind, Data: data}); err != nil {
+		return fmt.Errorf("no se pudo guardar el libro: %w", err)
+	}
+	return nil
+}
+
+func (l *Ledger) replay(raw json.RawMessage) error {
+	var e event
+	if err := json.Unmarshal(raw, &e); err != nil {
+		return err
+	}
+
+	switch e.Type {
+	case eventSplit:
+		split := &pb.Split{}
+		if err := protojson.Unmarshal(e.Data, split); err != nil {
+			return err
+		}
+		l.splits[split.MissionId] = split
+	case eventTransfer:
+		transfer := &pb.Transfer{}
+		if err := protojson.Unmarshal(e.Data, transfer); err != nil {
+			return err
+		}
+		l.transfers = append(l.transfers, transfer)
+	default:
+		return fmt.Errorf("evento desconocido %q", e.Type)
+	}
+	return nil
+}
+
+// Verify comprueba un pago a payee contra el reparto y las transferencias de
+// su misión en el libro: el reparto que viaja con el pago tiene que ser el
+// registrado, la parte tiene que cuadrar (ver VerifyShare), el pago tiene
+// que estar en el libro, con su payment_id si lo trae, y lo cobrado tiene
+// que coincidir con la parte acordada. Devuelve la parte esperada, o 0 si no
+// se conoce.
+func Verify(resp *pb.TransactionsResponse, payee string, payment *pb.PaymentRequest, params split.Params) (int32, error) {
+	if resp.Split == nil {
+		return 0, errors.New("no hay reparto registrado para la misión")
+	}
+	if payment.Split != nil && !proto.Equal(payment.Split, resp.Split) {
+		return 0, errors.New("el reparto enviado con el pago no coincide con el del libro")
+	}
+	share, err := VerifyShare(resp.Split, payee, payment.Amount, params)
+	if err != nil {
+		return share, err
+	}
+
+	var paid int64
+	found := false
+	for _, t := range resp.Transfers {
+		if t.Payee != payee || t.Payer == Loot {
+			continue
+		}
+		paid += int64(t.Amount)
+		found = found || (t.Amount == payment.Amount && t.PaymentId == payment.PaymentId)
+	}
+	if !found {
+		return share, fmt.Errorf("el pago de $%d no está en el libro", payment.Amount)
+	}
+	if paid != int64(share) {
+		return share, fmt.Errorf("el libro registra $%d y la parte acordada es $%d", paid, share)
+	}
+	return share, nil
+}
+
+// VerifyShare comprueba que amount sea la parte de payee en s y que s
+// respete su política, recalculada con params. Devuelve la parte esperada,
+// o 0 si no se conoce.
+func VerifyShare(s *pb.Split, payee string, amount int32, params split.Params) (int32, error) {
+	if err := split.Check(s, params); err != nil {
+		return 0, err
+	}
+	share, ok := s.Shares[payee]
+	if !ok {
+		return 0, fmt.Errorf("%s no figura en el reparto", payee)
+	}
+	if amount != share {
+		return share, fmt.Errorf("esperaba $%d de $%d (%s), recibí $%d, diferencia %+d",
+			share, s.TotalLoot, split.PolicyName(s.Policy), amount, amount-share)
+	}
+	return share, nil
+}
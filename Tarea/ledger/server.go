@@ -0,0 +1,65 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	pb "Tarea/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Server expone el libro como LedgerService.
+type Server struct {
+	pb.UnimplementedLedgerServiceServer
+	ledger *Ledger
+}
+
+// NewServer crea el servicio sobre ledger.
+func NewServer(ledger *Ledger) *Server {
+	return &Server{ledger: ledger}
+}
+
+func (s *Server) RecordSplit(ctx context.Context, req *pb.Split) (*pb.SplitResponse, error) {
+	if err := s.ledger.RecordSplit(req); err != nil {
+		return nil, grpcError(err)
+	}
+	log.Printf("[%s] Libro: reparto de $%d registrado (%d partes)", req.MissionId, req.TotalLoot, len(req.Shares))
+	return &pb.SplitResponse{Message: "Reparto registrado."}, nil
+}
+
+func (s *Server) RecordTransfer(ctx context.Context, req *pb.Transfer) (*pb.Transfer, error) {
+	transfer, err := s.ledger.Transfer(req)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	log.Printf("[%s] Libro: #%d %s -> %s $%d", transfer.MissionId, transfer.Id, transfer.Payer, transfer.Payee, transfer.Amount)
+	return transfer, nil
+}
+
+func (s *Server) GetBalance(ctx context.Context, req *pb.BalanceRequest) (*pb.BalanceResponse, error) {
+	if req.Party == "" {
+		return nil, status.Error(codes.InvalidArgument, "falta el personaje")
+	}
+	return s.ledger.Balance(req.Party), nil
+}
+
+func (s *Server) ListTransactions(ctx context.Context, req *pb.TransactionsRequest) (*pb.TransactionsResponse, error) {
+	return s.ledger.Transactions(req.Party, req.MissionId), nil
+}
+
+// grpcError traduce los errores del libro a códigos gRPC.
+func grpcError(err error) error {
+	switch {
+	case errors.Is(err, ErrInvalid):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, ErrConflict):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, ErrNoSplit), errors.Is(err, ErrOverpaid):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"log"
+	"maps"
+	"sort"
+	"strconv"
+	"strings"
+
+	pb "Tarea/proto"
+)
+
+// MissionHistory resume los reportes finales que recibió Lester.
+type MissionHistory struct {
+	Successes int
+	Failures  int
+	TotalLoot int64 // botín de las misiones exitosas
+	LostLoot  int64 // botín perdido en las fracasadas
+
+	FailuresByPhase     map[string]int
+	FailuresByCharacter map[string]int
+}
+
+func newMissionHistory() MissionHistory {
+	return MissionHistory{
+		FailuresByPhase:     make(map[string]int),
+		FailuresByCharacter: make(map[string]int),
+	}
+}
+
+// history devuelve una copia del historial. Requiere s.mu tomado.
+func (s *lesterServer) history() MissionHistory {
+	h := s.past
+	h.FailuresByPhase = maps.Clone(s.past.FailuresByPhase)
+	h.FailuresByCharacter = maps.Clone(s.past.FailuresByCharacter)
+	return h
+}
+
+// closeMission suma el reporte final al historial y descarta el registro de
+// la misión, que ya no se consulta. Requiere s.mu tomado.
+func (s *lesterServer) closeMission(report *pb.FinalReport) {
+	s.past.add(report)
+	delete(s.missions, report.MissionId)
+}
+
+// add suma un reporte final al historial.
+func (h *MissionHistory) add(report *pb.FinalReport) {
+	switch report.MissionOutcome {
+	case "success":
+		h.Successes++
+		h.TotalLoot += int64(report.TotalLoot)
+	case "failed":
+		h.Failures++
+		h.LostLoot += int64(report.LostLoot)
+		if report.FailedPhase != "" {
+			h.FailuresByPhase[report.FailedPhase]++
+		}
+		if report.CharacterFailed != "" {
+			h.FailuresByCharacter[report.CharacterFailed]++
+		}
+	}
+}
+
+func (h MissionHistory) log() {
+	log.Printf("Historial: %d exitosas ($%d), %d fracasadas ($%d perdidos)",
+		h.Successes, h.TotalLoot, h.Failures, h.LostLoot)
+	if h.Failures > 0 {
+		log.Printf("  Fracasos por fase: %s", formatCounts(h.FailuresByPhase))
+		log.Printf("  Fracasos por personaje: %s", formatCounts(h.FailuresByCharacter))
+	}
+}
+
+// formatCounts muestra los conteos ordenados de mayor a menor.
+func formatCounts(counts map[string]int) string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = key + "=" + strconv.Itoa(counts[key])
+	}
+	return strings.Join(parts, ", ")
+}
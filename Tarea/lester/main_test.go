@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"Tarea/journal"
+	"Tarea/negotiation"
+	"Tarea/offers"
+	pb "Tarea/proto"
+	"Tarea/seed"
+	"Tarea/starbus"
+)
+
+// newTestLester crea a Lester con las ofertas de ofertas.csv, sin
+// persistencia ni bus de estrellas.
+func newTestLester(t *testing.T, exclusive bool) *lesterServer {
+	t.Helper()
+
+	source, err := offers.NewCSV("../ofertas.csv", offers.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { source.Close() })
+	scorer, err := offers.NewScorer("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &lesterServer{
+		queue:        offers.NewQueue(source, scorer, exclusive),
+		policy:       negotiation.DefaultPolicy(),
+		activeStars:  make(map[string]*starNotifier),
+		clientStates: make(map[string]*ClientState),
+		missions:     make(map[string]*MissionRecord),
+		past:         newMissionHistory(),
+		rng:          seed.New(1, "lester"),
+	}
+}
+
+// TestConcurrentOffers hace que varios clientes pidan, rechacen y acepten
+// ofertas a la vez; con ofertas exclusivas nadie puede quedarse con una que
+// ya tomó otro.
+func TestConcurrentOffers(t *testing.T) {
+	s := newTestLester(t, true)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	confirmed := make(map[offers.Offer]string)
+	for i := 0; i < 8; i++ {
+		requester := fmt.Sprintf("Michael-%d", i)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for attempt := 0; attempt < 20; attempt++ {
+				offer, err := s.GetOffer(ctx, &pb.OfferRequest{Requester: requester})
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if !offer.HasOffer {
+					continue
+				}
+
+				// Rechaza una de cada dos para ejercitar el contador de rechazos
+				accept := attempt%2 == 1
+				missionID := fmt.Sprintf("%s-%d", requester, attempt)
+				resp, err := s.ConfirmDecision(ctx, &pb.DecisionRequest{Requester: requester, Accepted: accept, MissionId: missionID})
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if !resp.Confirmed {
+					continue
+				}
+
+				taken := offers.Offer{
+					Loot:            offer.Loot,
+					SuccessFranklin: offer.SuccessFranklin,
+					SuccessTrevor:   offer.SuccessTrevor,
+					PoliceRisk:      offer.PoliceRisk,
+				}
+				mu.Lock()
+				if other, dup := confirmed[taken]; dup {
+					t.Errorf("%s y %s aceptaron la misma oferta %+v", other, requester, taken)
+				}
+				confirmed[taken] = requester
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.accepted) != len(confirmed) {
+		t.Errorf("Lester registró %d ofertas aceptadas, los clientes confirmaron %d", len(s.accepted), len(confirmed))
+	}
+	if len(confirmed) == 0 {
+		t.Error("ningún cliente confirmó una oferta")
+	}
+}
+
+// TestSeededReplay repite una misión con la misma semilla contra el mismo
+// Lester: el azar empieza igual, pero la cola y la espera del cliente siguen
+// donde quedaron.
+func TestSeededReplay(t *testing.T) {
+	s := newTestLester(t, false)
+	ctx := context.Background()
+
+	draws := func(missionID string) []int {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		rng := s.random("Michael", s.clientState("Michael"), &pb.OfferRequest{Seed: 42, MissionId: missionID})
+		var drawn []int
+		for i := 0; i < 5; i++ {
+			drawn = append(drawn, rng.Intn(100))
+		}
+		return drawn
+	}
+	if first, second := draws("m1"), draws("m2"); !reflect.DeepEqual(first, second) {
+		t.Errorf("azar de la misión repetida %v, quería %v", second, first)
+	}
+
+	s.mu.Lock()
+	state := s.clientState("Michael")
+	state.currentOffer = 3
+	s.mu.Unlock()
+	if _, err := s.GetOffer(ctx, &pb.OfferRequest{Requester: "Michael", Seed: 42, MissionId: "m3"}); err != nil {
+		t.Fatal(err)
+	}
+	s.mu.Lock()
+	if state.currentOffer < 3 {
+		t.Errorf("la misión nueva volvió a la oferta %d", state.currentOffer+1)
+	}
+	state.cooldownUntil = time.Now().Add(time.Minute)
+	s.mu.Unlock()
+
+	offer, err := s.GetOffer(ctx, &pb.OfferRequest{Requester: "Michael", Seed: 42, MissionId: "m4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offer.HasOffer || offer.RetryAfterMs == 0 {
+		t.Errorf("la misión nueva no respetó la espera: %v", offer)
+	}
+}
+
+// TestRestartStars reinicia las estrellas de un golpe, como al reintentarlo:
+// el envío anterior no debe seguir publicando junto al nuevo.
+func TestRestartStars(t *testing.T) {
+	s := newTestLester(t, false)
+	bus := starbus.NewMemory()
+	s.starBus = bus
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stars, err := bus.Subscribe(ctx, "Franklin", "m")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := &pb.StarRequest{Character: "Franklin", PoliceRisk: 90, MissionId: "m"}
+	s.StartStarNotifications(ctx, start)
+	s.StopStarNotifications(ctx, &pb.StopRequest{Character: "Franklin", MissionId: "m"})
+	s.StartStarNotifications(ctx, start)
+
+	// Con riesgo 90 sale una estrella por segundo
+	deadline := time.After(1500 * time.Millisecond)
+	received := 0
+	for {
+		select {
+		case <-stars:
+			received++
+		case <-deadline:
+			if received != 1 {
+				t.Errorf("llegaron %d estrellas en el primer segundo, quería 1", received)
+			}
+			s.StopStarNotifications(ctx, &pb.StopRequest{Character: "Franklin", MissionId: "m"})
+			return
+		}
+	}
+}
+
+// TestCompactJournal repite pedidos que no cambian nada y reinicia a Lester:
+// el journal solo guarda los cambios y al arrancar se compacta sin perder
+// estado.
+func TestCompactJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lester.jsonl")
+	open := func() *lesterServer {
+		s := newTestLester(t, false)
+		var err error
+		if s.journal, err = journal.Open(path, s.replay); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.compact(); err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	lines := func() int {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return bytes.Count(data, []byte("\n"))
+	}
+	ctx := context.Background()
+
+	s := open()
+	for _, missionID := range []string{"m1", "m2"} {
+		// Pedir la misma oferta una y otra vez no cambia el estado
+		for i := 0; i < 20; i++ {
+			if _, err := s.GetOffer(ctx, &pb.OfferRequest{Requester: "Michael"}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := s.ConfirmDecision(ctx, &pb.DecisionRequest{Requester: "Michael", Accepted: true, MissionId: missionID}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.SendFinalReport(ctx, &pb.FinalReport{MissionId: "m1", MissionOutcome: "success", TotalLoot: 1000})
+	// historial inicial, aceptada y cliente tras cada decisión, y el reporte
+	if got := lines(); got != 6 {
+		t.Errorf("el journal tiene %d líneas, quería 6", got)
+	}
+	want := s.clientStates["Michael"].snapshot()
+	s.journal.Close()
+
+	s = open()
+	defer s.journal.Close()
+	// historial, cliente y las 2 aceptadas
+	if got := lines(); got != 4 {
+		t.Errorf("el journal compactado tiene %d líneas, quería 4", got)
+	}
+	if got := s.clientStates["Michael"].snapshot(); got != want {
+		t.Errorf("cliente recuperado %+v, quería %+v", got, want)
+	}
+	if len(s.accepted) != 2 || s.past.Successes != 1 {
+		t.Errorf("recuperadas %d aceptadas y %d exitosas, quería 2 y 1", len(s.accepted), s.past.Successes)
+	}
+	if _, open := s.missions["m1"]; open {
+		t.Error("la misión cerrada volvió a abrirse")
+	}
+	if record, open := s.missions["m2"]; !open || record.terms == nil {
+		t.Error("la misión en curso perdió sus términos")
+	}
+}
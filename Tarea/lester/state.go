@@ -0,0 +1,269 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"Tarea/dedup"
+	"Tarea/offers"
+	pb "Tarea/proto"
+
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// Tipos de evento del journal de Lester.
+const (
+	eventClient   = "client"   // cambió el estado de un cliente
+	eventAccepted = "accepted" // un cliente aceptó una oferta
+	eventPayment  = "payment"  // Lester recibió un pago
+	eventReport   = "report"   // Lester recibió un reporte final
+	eventHistory  = "history"  // resumen de los reportes, al compactar
+)
+
+// stateEvent es una línea del journal. Solo se llenan los campos del tipo.
+type stateEvent struct {
+	Type          string          `json:"type"`
+	Time          time.Time       `json:"time"`
+	Requester     string          `json:"requester,omitempty"`
+	CurrentOffer  int             `json:"current_offer,omitempty"`
+	LastOffer     *offers.Offer   `json:"last_offer,omitempty"`
+	RejectedCount int             `json:"rejected_count,omitempty"`
+	CooldownUntil *time.Time      `json:"cooldown_until,omitempty"`
+	Offer         *offers.Offer   `json:"offer,omitempty"`
+	Terms         json.RawMessage `json:"terms,omitempty"`
+	MissionID     string          `json:"mission_id,omitempty"`
+	Amount        int32           `json:"amount,omitempty"`
+	PaymentID     string          `json:"payment_id,omitempty"`
+	Response      json.RawMessage `json:"response,omitempty"`
+	Report        json.RawMessage `json:"report,omitempty"`
+	History       *MissionHistory `json:"history,omitempty"`
+}
+
+// AcceptedOffer es un contrato que un cliente aceptó.
+type AcceptedOffer struct {
+	Requester string
+	MissionID string
+	Offer     offers.Offer
+	Time      time.Time
+}
+
+// savedClient es lo que el journal guarda del estado de un cliente.
+type savedClient struct {
+	currentOffer  int
+	last          offers.Offer
+	hasLast       bool
+	rejectedCount int
+	cooldownUntil time.Time
+}
+
+func (state *ClientState) snapshot() savedClient {
+	saved := savedClient{
+		currentOffer:  state.currentOffer,
+		hasLast:       state.last != nil,
+		rejectedCount: state.rejectedCount,
+		cooldownUntil: state.cooldownUntil,
+	}
+	if state.last != nil {
+		saved.last = *state.last
+	}
+	return saved
+}
+
+// record agrega el evento al journal. Requiere s.mu tomado para que los
+// eventos queden en el mismo orden en que se aplicaron.
+func (s *lesterServer) record(event stateEvent) {
+	event.Time = time.Now()
+	if err := s.journal.Append(event); err != nil {
+		log.Printf("Error guardando estado (%s): %v", event.Type, err)
+	}
+}
+
+// recordClient guarda el estado del cliente si cambió desde la última vez.
+func (s *lesterServer) recordClient(requester string, state *ClientState) {
+	saved := state.snapshot()
+	if saved == state.saved {
+		return
+	}
+	state.saved = saved
+	s.record(clientEvent(requester, state))
+}
+
+func clientEvent(requester string, state *ClientState) stateEvent {
+	event := stateEvent{
+		Type:          eventClient,
+		Requester:     requester,
+		CurrentOffer:  state.currentOffer,
+		LastOffer:     state.last,
+		RejectedCount: state.rejectedCount,
+	}
+	if !state.cooldownUntil.IsZero() {
+		event.CooldownUntil = &state.cooldownUntil
+	}
+	return event
+}
+
+func (s *lesterServer) recordAccepted(requester, missionID string, offer offers.Offer, terms *pb.Terms) {
+	event := stateEvent{Type: eventAccepted, Requester: requester, MissionID: missionID, Offer: &offer}
+	if terms != nil {
+		data, err := protojson.Marshal(terms)
+		if err != nil {
+			log.Printf("Error guardando términos: %v", err)
+		}
+		event.Terms = data
+	}
+	s.record(event)
+}
+
+// recordPayment guarda el pago con su respuesta, para responder igual a un
+// reintento después de reiniciar.
+func (s *lesterServer) recordPayment(req *pb.PaymentRequest, resp *pb.PaymentResponse) {
+	data, err := protojson.Marshal(resp)
+	if err != nil {
+		log.Printf("Error guardando pago: %v", err)
+		return
+	}
+	event := stateEvent{
+		Type:      eventPayment,
+		MissionID: req.MissionId,
+		Amount:    req.Amount,
+		PaymentID: req.PaymentId,
+		Response:  data,
+	}
+	s.record(event)
+	record := s.mission(req.MissionId)
+	record.paid = append(record.paid, event)
+}
+
+func (s *lesterServer) recordReport(report *pb.FinalReport) {
+	data, err := protojson.Marshal(report)
+	if err != nil {
+		log.Printf("Error guardando reporte: %v", err)
+		return
+	}
+	s.record(stateEvent{Type: eventReport, MissionID: report.MissionId, Report: data})
+}
+
+// replay aplica un evento del journal al arrancar.
+func (s *lesterServer) replay(raw json.RawMessage) error {
+	var event stateEvent
+	if err := json.Unmarshal(raw, &event); err != nil {
+		return err
+	}
+
+	switch event.Type {
+	case eventClient:
+		state := &ClientState{
+			currentOffer:  event.CurrentOffer,
+			last:          event.LastOffer,
+			rejectedCount: event.RejectedCount,
+		}
+		if event.CooldownUntil != nil {
+			state.cooldownUntil = *event.CooldownUntil
+		}
+		state.saved = state.snapshot()
+		s.clientStates[event.Requester] = state
+	case eventAccepted:
+		if event.Offer == nil {
+			return fmt.Errorf("evento accepted sin oferta")
+		}
+		s.accepted = append(s.accepted, AcceptedOffer{Requester: event.Requester, MissionID: event.MissionID, Offer: *event.Offer, Time: event.Time})
+		s.queue.Take(*event.Offer)
+		if event.MissionID != "" && len(event.Terms) > 0 {
+			terms := &pb.Terms{}
+			if err := protojson.Unmarshal(event.Terms, terms); err != nil {
+				return err
+			}
+			s.mission(event.MissionID).terms = terms
+		}
+	case eventPayment:
+		// Los journals anteriores solo tienen los pagos correctos, sin respuesta
+		resp := &pb.PaymentResponse{CorrectAmount: true}
+		if len(event.Response) > 0 {
+			if err := protojson.Unmarshal(event.Response, resp); err != nil {
+				return err
+			}
+		}
+		record := s.mission(event.MissionID)
+		if resp.CorrectAmount {
+			record.payments += event.Amount
+		}
+		record.paid = append(record.paid, event)
+		if key := dedup.Key(&pb.PaymentRequest{MissionId: event.MissionID, PaymentId: event.PaymentID}); key != "" {
+			s.payments.Put(key, resp)
+		}
+	case eventReport:
+		report := &pb.FinalReport{}
+		if err := protojson.Unmarshal(event.Report, report); err != nil {
+			return err
+		}
+		s.closeMission(report)
+	case eventHistory:
+		if event.History == nil {
+			return fmt.Errorf("evento history sin historial")
+		}
+		s.past = *event.History
+		if s.past.FailuresByPhase == nil {
+			s.past.FailuresByPhase = make(map[string]int)
+		}
+		if s.past.FailuresByCharacter == nil {
+			s.past.FailuresByCharacter = make(map[string]int)
+		}
+	default:
+		return fmt.Errorf("evento desconocido %q", event.Type)
+	}
+	return nil
+}
+
+// compact reescribe el journal con los eventos que reconstruyen el estado
+// actual: el historial resumido, el último estado de cada cliente, las
+// ofertas aceptadas y los pagos de las misiones en curso. Se llama al
+// arrancar, así el journal no crece con cada pedido repetido.
+func (s *lesterServer) compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	history := s.history()
+	events := []any{stateEvent{Type: eventHistory, Time: now, History: &history}}
+
+	requesters := make([]string, 0, len(s.clientStates))
+	for requester := range s.clientStates {
+		requesters = append(requesters, requester)
+	}
+	sort.Strings(requesters)
+	for _, requester := range requesters {
+		event := clientEvent(requester, s.clientStates[requester])
+		event.Time = now
+		events = append(events, event)
+	}
+
+	// Las misiones ya cerradas solo conservan la oferta tomada
+	for _, accepted := range s.accepted {
+		offer := accepted.Offer
+		event := stateEvent{Type: eventAccepted, Time: accepted.Time, Requester: accepted.Requester, Offer: &offer}
+		if record, open := s.missions[accepted.MissionID]; open && record.terms != nil {
+			data, err := protojson.Marshal(record.terms)
+			if err != nil {
+				return err
+			}
+			event.MissionID, event.Terms = accepted.MissionID, data
+		}
+		events = append(events, event)
+	}
+
+	missionIDs := make([]string, 0, len(s.missions))
+	for id := range s.missions {
+		missionIDs = append(missionIDs, id)
+	}
+	sort.Strings(missionIDs)
+	for _, id := range missionIDs {
+		for _, event := range s.missions[id].paid {
+			events = append(events, event)
+		}
+	}
+
+	return s.journal.Rewrite(events)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RPCError es una llamada a otro servicio que falló incluso tras los
+// reintentos.
+type RPCError struct {
+	Op     string // p.ej. "StartGolpe"
+	Target string // a quién se llamó: Lester o el personaje
+	Err    error
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("%s a %s: %v", e.Op, e.Target, e.Err)
+}
+
+func (e *RPCError) Unwrap() error { return e.Err }
+
+// Reintentos de las llamadas con errores transitorios.
+const (
+	retryAttempts = 4
+	retryBackoff  = 250 * time.Millisecond // se duplica en cada intento
+	retryTimeout  = 5 * time.Second        // por intento
+)
+
+// transient indica si vale la pena reintentar una llamada que devolvió err.
+func transient(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted:
+		return true
+	}
+	return false
+}
+
+// unsent indica si err asegura que el pedido no llegó al servidor: la
+// conexión no estaba disponible.
+func unsent(err error) bool {
+	return status.Code(err) == codes.Unavailable
+}
+
+// retry ejecuta call hasta que funcione, falle con un error no transitorio
+// o se agoten los intentos, esperando cada vez el doble. Cada intento tiene
+// su propio timeout. El error final es un *RPCError.
+//
+// Tras un timeout el pedido pudo haberse aplicado, así que call debe ser
+// idempotente en el servidor; para las que no lo son está retryUnsent.
+func retry(ctx context.Context, op, target string, call func(ctx context.Context) error) error {
+	return retryIf(ctx, op, target, transient, call)
+}
+
+// retryUnsent es retry para llamadas que no son idempotentes: solo reintenta
+// si el pedido no llegó al servidor.
+func retryUnsent(ctx context.Context, op, target string, call func(ctx context.Context) error) error {
+	return retryIf(ctx, op, target, unsent, call)
+}
+
+func retryIf(ctx context.Context, op, target string, retriable func(error) bool, call func(ctx context.Context) error) error {
+	backoff := retryBackoff
+	var err error
+	for attempt := 1; attempt <= retryAttempts; attempt++ {
+		callCtx, cancel := context.WithTimeout(ctx, retryTimeout)
+		err = call(callCtx)
+		cancel()
+		if err == nil || !retriable(err) || ctx.Err() != nil || attempt == retryAttempts {
+			break
+		}
+
+		log.Printf("%s a %s falló (%v). Reintentando en %s...", op, target, err, backoff)
+		select {
+		case <-ctx.Done():
+			return &RPCError{Op: op, Target: target, Err: ctx.Err()}
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+	if err != nil {
+		return &RPCError{Op: op, Target: target, Err: err}
+	}
+	return nil
+}
@@ -5,403 +5,770 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
 	"time"
 
+	"Tarea/config"
+	"Tarea/evaluator"
+	"Tarea/negotiation"
+	"Tarea/offers"
+	"Tarea/pipeline"
+	"Tarea/planner"
 	pb "Tarea/proto"
+	"Tarea/report"
+	"Tarea/seed"
+	"Tarea/split"
 
 	"google.golang.org/grpc"
 )
 
-func startDistractionPhase(client pb.MissionServiceClient, character string, successRate int32) bool {
-	// Calcular turnos necesarios
-	turnsRequired := 200 - successRate
+// buildRoster arma el roster con los miembros configurados. La tasa de éxito
+// es la que Lester evaluó en la oferta o, si no lo evaluó, la configurada.
+func buildRoster(cfg *config.Config, offer *pb.OfferResponse) []planner.Member {
+	var roster []planner.Member
+	for _, endpoint := range cfg.CrewEndpoints() {
+		rate, ok := offer.SuccessRates[endpoint.Name]
+		if !ok {
+			if endpoint.SuccessRate <= 0 {
+				log.Printf("%s no aparece en la oferta ni tiene success_rate configurado; queda fuera del plan", endpoint.Name)
+				continue
+			}
+			rate = endpoint.SuccessRate
+		}
+		roster = append(roster, planner.Member{
+			Name:        endpoint.Name,
+			Address:     endpoint.Address,
+			SuccessRate: rate,
+		})
+	}
+	return roster
+}
+
+// crewClients mantiene una conexión por miembro del equipo.
+type crewClients map[string]*grpc.ClientConn
 
+func (c crewClients) client(member planner.Member) (pb.MissionServiceClient, error) {
+	conn, ok := c[member.Name]
+	if !ok {
+		var err error
+		conn, err = grpc.Dial(member.Address, grpc.WithInsecure())
+		if err != nil {
+			return nil, &RPCError{Op: "Dial", Target: member.Name, Err: err}
+		}
+		c[member.Name] = conn
+	}
+	return pb.NewMissionServiceClient(conn), nil
+}
+
+func (c crewClients) Close() {
+	for _, conn := range c {
+		conn.Close()
+	}
+}
+
+// startDistractionPhase envía al personaje a distraer y devuelve el estado
+// final, o nil si la fase se interrumpió. observe recibe cada estado.
+func startDistractionPhase(ctx context.Context, client pb.MissionServiceClient, missionID, character string, turnsRequired int32,
+	phaseSeed int64, observe func(*pb.StatusResponse)) (*pb.StatusResponse, error) {
 	log.Printf("Enviando a %s a mision de distraccion (%d turnos)", character, turnsRequired)
 
 	// Iniciar distraccion
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	_, err := client.StartDistraction(ctx, &pb.DistractionRequest{
-		RequiredTurns:     turnsRequired,
-		AssignedCharacter: character,
+	err := retry(ctx, "StartDistraction", character, func(ctx context.Context) error {
+		_, err := client.StartDistraction(ctx, &pb.DistractionRequest{
+			RequiredTurns:     turnsRequired,
+			AssignedCharacter: character,
+			MissionId:         missionID,
+			Seed:              phaseSeed,
+		})
+		return err
 	})
-
 	if err != nil {
-		log.Fatalf("Error iniciando distraccion: %v", err)
-		return false
+		return nil, err
 	}
 
 	// Monitorear progreso
-	for {
-		time.Sleep(1 * time.Second)
-
-		statusResp, err := client.CheckStatus(ctx, &pb.StatusRequest{Character: character})
-		if err != nil {
-			log.Fatalf("Error consultando estado: %v", err)
-			return false
-		}
-
+	statusResp, err := watchStatus(ctx, client, missionID, character, func(statusResp *pb.StatusResponse) {
 		log.Printf("Estado de %s: %s (%d/%d turnos)",
 			character, statusResp.Status, statusResp.TurnsCompleted, statusResp.TotalTurns)
+		observe(statusResp)
+	})
 
-		if statusResp.Status == "success" {
-			return true
-		}
-		if statusResp.Status == "failed" {
-			return false
-		}
+	if err != nil || statusResp.Status != "success" {
+		abortMission(client, nil, missionID, character, "Fase de distraccion fracasada")
 	}
+	if err != nil && ctx.Err() == nil {
+		return nil, err
+	}
+	return statusResp, nil
 }
 
-func startGolpePhase(missionClient pb.MissionServiceClient, notificationClient pb.NotificationServiceClient,
-	character string, successRate int32, policeRisk int32, baseLoot int32) (bool, int32) {
+// startGolpePhase envía al personaje al golpe con las estrellas de Lester
+// activas y devuelve el estado final, o nil si la fase se interrumpió.
+// observe recibe cada estado.
+func startGolpePhase(ctx context.Context, missionClient pb.MissionServiceClient, notificationClient pb.NotificationServiceClient,
+	missionID, character string, turnsRequired int32, policeRisk int32, baseLoot int32,
+	phaseSeed int64, observe func(*pb.StatusResponse)) (*pb.StatusResponse, error) {
 
-	turnsRequired := 200 - successRate
 	log.Printf("Enviando a %s a mision de golpe (%d turnos)", character, turnsRequired)
 
-	// Iniciar notificaciones de estrellas
-	ctxNotify := context.Background()
-	_, err := notificationClient.StartStarNotifications(ctxNotify, &pb.StarRequest{
-		Character:  character,
-		PoliceRisk: policeRisk,
+	// Iniciar golpe
+	var golpe *pb.GolpeResponse
+	err := retry(ctx, "StartGolpe", character, func(ctx context.Context) error {
+		var err error
+		golpe, err = missionClient.StartGolpe(ctx, &pb.GolpeRequest{
+			RequiredTurns:     turnsRequired,
+			AssignedCharacter: character,
+			PoliceRisk:        policeRisk,
+			BaseLoot:          baseLoot,
+			MissionId:         missionID,
+			Seed:              phaseSeed,
+		})
+		return err
 	})
 	if err != nil {
-		log.Fatalf("Error iniciando notificaciones: %v", err)
-		return false, 0
+		abortMission(missionClient, nil, missionID, character, "No se pudo iniciar el golpe")
+		return nil, err
 	}
 
-	// Iniciar golpe
-	ctxGolpe := context.Background()
-	_, err = missionClient.StartGolpe(ctxGolpe, &pb.GolpeRequest{
-		RequiredTurns:     turnsRequired,
-		AssignedCharacter: character,
-		PoliceRisk:        policeRisk,
-		BaseLoot:          baseLoot,
+	// Iniciar notificaciones de estrellas, por turno del miembro: la primera
+	// llega medio intervalo antes de su turno, así que el golpe recién
+	// iniciado no la pierde
+	err = retry(ctx, "StartStarNotifications", "Lester", func(ctx context.Context) error {
+		_, err := notificationClient.StartStarNotifications(ctx, &pb.StarRequest{
+			Character:      character,
+			PoliceRisk:     policeRisk,
+			MissionId:      missionID,
+			ByTurn:         true,
+			StartId:        strconv.FormatInt(phaseSeed, 10), // cada intento tiene su semilla
+			TurnDurationUs: golpe.TurnDurationUs,
+		})
+		return err
 	})
 	if err != nil {
-		log.Fatalf("Error iniciando golpe: %v", err)
-		return false, 0
+		abortMission(missionClient, notificationClient, missionID, character, "No se pudieron iniciar las estrellas")
+		return nil, err
 	}
 
 	// Monitorear progreso
-	ctxMonitor := context.Background()
-	var totalLoot int32 = baseLoot
-
-	for {
-		time.Sleep(1 * time.Second)
-
-		statusResp, err := missionClient.CheckStatus(ctxMonitor, &pb.StatusRequest{Character: character})
-		if err != nil {
-			log.Fatalf("Error consultando estado: %v", err)
-			return false, 0
-		}
-
+	statusResp, err := watchStatus(ctx, missionClient, missionID, character, func(statusResp *pb.StatusResponse) {
 		log.Printf("Estado de %s: %s (%d/%d turnos, %d estrellas, +$%d)",
 			character, statusResp.Status, statusResp.TurnsCompleted,
 			statusResp.TotalTurns, statusResp.CurrentStars, statusResp.ExtraLoot)
+		observe(statusResp)
+	})
 
-		if statusResp.Status == "success" {
-			totalLoot += statusResp.ExtraLoot
-
-			// Detener notificaciones
-			_, err := notificationClient.StopStarNotifications(ctxMonitor, &pb.StopRequest{Character: character})
-			if err != nil {
-				log.Printf("Error deteniendo notificaciones: %v", err)
-			}
-
-			return true, totalLoot
-		}
-		if statusResp.Status == "failed" {
-			// Detener notificaciones
-			notificationClient.StopStarNotifications(ctxMonitor, &pb.StopRequest{Character: character})
-			return false, 0
+	if err == nil && statusResp.Status == "success" {
+		// Detener notificaciones
+		_, err := notificationClient.StopStarNotifications(ctx, &pb.StopRequest{Character: character, MissionId: missionID})
+		if err != nil {
+			log.Printf("Error deteniendo notificaciones: %v", err)
 		}
+		return statusResp, nil
 	}
+
+	// Abortar la misión y detener notificaciones
+	abortMission(missionClient, notificationClient, missionID, character, "Fase de golpe fracasada")
+	if err != nil && ctx.Err() == nil {
+		return nil, err
+	}
+	return statusResp, nil
 }
 
-func generateSuccessReport(baseLoot, extraLoot, totalLoot, franklinShare, trevorShare, lesterShare, lesterExtra int32, missionID int) {
-	file, err := os.Create("/root/reports/Reporte.txt")
+// abortMission cancela la misión del personaje en su servidor y, si se indica
+// notificationClient, detiene las estrellas de Lester. Usa un contexto propio
+// porque suele llamarse cuando el de la misión ya fue cancelado.
+func abortMission(missionClient pb.MissionServiceClient, notificationClient pb.NotificationServiceClient,
+	missionID, character, reason string) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp, err := missionClient.AbortMission(ctx, &pb.AbortRequest{
+		Character: character,
+		MissionId: missionID,
+		Reason:    reason,
+	})
 	if err != nil {
-		log.Printf("Error creando reporte: %v", err)
+		log.Printf("Error abortando mision de %s: %v", character, err)
+	} else {
+		log.Printf("Abortar mision de %s: %s", character, resp.Message)
+	}
+
+	if notificationClient == nil {
 		return
 	}
-	defer file.Close()
-
-	content := fmt.Sprintf(`=========================================================
-== REPORTE FINAL DE LA MISION ==
-=========================================================
-Mision: Asalto al Banco #%d
-Resultado Global: MISION COMPLETADA CON EXITO!
-
---- REPARTO DEL BOTIN ---
-Botin Base: $%d
-Botin Extra (Habilidad de Chop): $%d
-Botin Total: $%d
-
---------------------------------------------------------
-Pago a Franklin: $%d
-Pago a Trevor: $%d
-Pago a Lester: $%d (reparto) + $%d (resto)
---------------------------------------------------------
-Saldo Final de la Operacion: $%d
-=========================================================`,
-		missionID, baseLoot, extraLoot, totalLoot,
-		franklinShare, trevorShare,
-		lesterShare-lesterExtra, lesterExtra,
-		totalLoot)
-
-	file.WriteString(content)
-	log.Println("Reporte.txt generado exitosamente")
+	_, err = notificationClient.StopStarNotifications(ctx, &pb.StopRequest{Character: character, MissionId: missionID})
+	if err != nil {
+		log.Printf("Error deteniendo notificaciones: %v", err)
+	}
 }
 
-func generateFailureReport(phase string, character string, lostLoot int32, reason string, missionID int) {
-	file, err := os.Create("/root/reports/Reporte.txt")
-	if err != nil {
-		log.Printf("Error creando reporte: %v", err)
-		return
+// negotiate regatea con Lester los términos de la oferta pendiente. Lester
+// recuerda el acuerdo y lo devuelve al confirmar; si no hay acuerdo quedan
+// los términos de entrada.
+func negotiate(ctx context.Context, client pb.LesterServiceClient, bargainer negotiation.Bargainer, standard *pb.Terms) {
+	proposal := bargainer.Open(standard)
+	for round := int32(1); ; round++ {
+		resp, err := client.Negotiate(ctx, &pb.NegotiateRequest{
+			Requester: "Michael",
+			Proposal:  proposal,
+			Round:     round,
+		})
+		if err != nil {
+			log.Printf("Error negociando con Lester: %v", err)
+			return
+		}
+		log.Printf("Regateo ronda %d: propuse Lester=%d%%, Riesgo=%d%%; Lester: %s (%s)",
+			round, proposal.LesterCut, proposal.PoliceRisk, resp.Verdict, resp.Message)
+
+		if resp.Verdict != negotiation.Counter {
+			return
+		}
+		proposal = bargainer.Reply(proposal, resp.Terms)
 	}
-	defer file.Close()
+}
 
-	content := fmt.Sprintf(`=========================================================
-== REPORTE FINAL DE LA MISION ==
-=========================================================
-Mision: Asalto al Banco #%d
-Resultado Global: MISION FRACASADA
+// watchStatus consume el stream de estado del personaje hasta que la misión
+// termina y devuelve el último estado recibido. Si el stream se corta por un
+// error transitorio se vuelve a abrir: el servidor reenvía el estado actual.
+func watchStatus(ctx context.Context, client pb.MissionServiceClient, missionID, character string,
+	logStatus func(*pb.StatusResponse)) (*pb.StatusResponse, error) {
 
---- DETALLES DEL FRACASO ---
-Fase de Fracaso: %s
-Personaje Responsable: %s
-Botin Perdido: $%d
-Motivo: %s
-=========================================================`,
-		missionID, phase, character, lostLoot, reason)
+	for {
+		var stream pb.MissionService_WatchStatusClient
+		err := retry(ctx, "WatchStatus", character, func(_ context.Context) error {
+			var err error
+			stream, err = client.WatchStatus(ctx, &pb.StatusRequest{Character: character, MissionId: missionID})
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
 
-	file.WriteString(content)
-	log.Printf("Reporte de fracaso generado: Fase %s, %s fracaso por %s", phase, character, reason)
-}
+		for {
+			statusResp, err := stream.Recv()
+			if err == io.EOF {
+				return nil, &RPCError{Op: "WatchStatus", Target: character, Err: fmt.Errorf("el stream terminó sin resultado")}
+			}
+			if err != nil {
+				if transient(err) && ctx.Err() == nil {
+					log.Printf("Se cortó el estado de %s (%v). Reconectando...", character, err)
+					break
+				}
+				return nil, &RPCError{Op: "WatchStatus", Target: character, Err: err}
+			}
 
-func main() {
-	missionID := int(time.Now().Unix() % 10000)
+			logStatus(statusResp)
 
-	// FASE 1: Conexion con Lester
-	lesterConn, err := grpc.Dial("localhost:50061", grpc.WithInsecure())
-	if err != nil {
-		log.Fatalf("No se pudo conectar a Lester: %v", err)
+			if statusResp.Status == "success" || statusResp.Status == "failed" || statusResp.Status == "aborted" {
+				return statusResp, nil
+			}
+		}
 	}
-	defer lesterConn.Close()
-
-	lesterClient := pb.NewLesterServiceClient(lesterConn)
-	var accepted bool
-	var currentOffer *pb.OfferResponse
+}
 
-	// Negociacion con Lester
-	for !accepted {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
+// findContract pide ofertas a Lester hasta que Michael acepta una y Lester
+// la confirma. Devuelve la oferta y los términos acordados.
+func findContract(lesterClient pb.LesterServiceClient, cfg *config.Config, missionID string,
+	offerEvaluator evaluator.OfferEvaluator) (*pb.OfferResponse, *pb.Terms, error) {
 
-		offer, err := lesterClient.GetOffer(ctx, &pb.OfferRequest{Requester: "Michael"})
+	ctx := context.Background()
+	for {
+		// Cada pedido que llega consume azar de Lester; repetir uno que ya
+		// llegó cambiaría la corrida
+		var offer *pb.OfferResponse
+		err := retryUnsent(ctx, "GetOffer", "Lester", func(ctx context.Context) error {
+			var err error
+			offer, err = lesterClient.GetOffer(ctx, &pb.OfferRequest{
+				Requester: "Michael",
+				Seed:      seed.Derive(cfg.Seed, "lester"),
+				MissionId: missionID,
+			})
+			return err
+		})
 		if err != nil {
-			log.Fatalf("Error obteniendo oferta: %v", err)
+			return nil, nil, err
 		}
 
 		if !offer.HasOffer {
-			log.Println("Lester no tiene ofertas. Reintentando...")
-			time.Sleep(2 * time.Second)
+			// Lester indica cuánto esperar tras varios rechazos
+			wait := 2 * time.Second
+			if offer.RetryAfterMs > 0 {
+				wait = time.Duration(offer.RetryAfterMs) * time.Millisecond
+				log.Printf("Lester pide esperar %s antes de otra oferta", wait)
+			} else {
+				log.Println("Lester no tiene ofertas. Reintentando...")
+			}
+			time.Sleep(wait)
 			continue
 		}
 
 		log.Printf("Oferta: Botin=%d, Franklin=%d%%, Trevor=%d%%, Riesgo=%d%%",
 			offer.Loot, offer.SuccessFranklin, offer.SuccessTrevor, offer.PoliceRisk)
 
-		if (offer.SuccessFranklin > 50 || offer.SuccessTrevor > 50) && offer.PoliceRisk < 80 {
-			accepted = true
-			currentOffer = offer
-			resp, _ := lesterClient.ConfirmDecision(ctx, &pb.DecisionRequest{
-				Requester: "Michael",
-				Accepted:  true,
-			})
-			log.Println("Decision:", resp.Message)
-		} else {
-			resp, _ := lesterClient.ConfirmDecision(ctx, &pb.DecisionRequest{
+		ok, reason := offerEvaluator.Accept(toOffer(offer))
+		log.Printf("Evaluacion (%s): %s", cfg.OfferEvaluator, reason)
+
+		standard := &pb.Terms{Loot: offer.Loot, PoliceRisk: offer.PoliceRisk, LesterCut: offer.LesterCut}
+		if ok && cfg.Bargaining.Bargains() {
+			negotiateCtx, cancel := context.WithTimeout(ctx, retryTimeout)
+			negotiate(negotiateCtx, lesterClient, cfg.Bargaining, standard)
+			cancel()
+		}
+
+		var resp *pb.DecisionResponse
+		err = retry(ctx, "ConfirmDecision", "Lester", func(ctx context.Context) error {
+			var err error
+			resp, err = lesterClient.ConfirmDecision(ctx, &pb.DecisionRequest{
 				Requester: "Michael",
-				Accepted:  false,
+				Accepted:  ok,
+				MissionId: missionID,
 			})
-			log.Println("Decision:", resp.Message)
+			return err
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		log.Println("Decision:", resp.Message)
+
+		// Otro equipo pudo haber tomado el trabajo antes
+		if ok && resp.Confirmed {
+			if resp.Terms == nil {
+				return offer, standard, nil
+			}
+			return offer, resp.Terms, nil
+		}
+		if !ok {
 			time.Sleep(2 * time.Second)
 		}
 	}
+}
 
-	log.Println("Michael acepto un contrato valido.")
+// sendFinalReport envía el reporte final a Lester. Un error solo se registra:
+// el reporte local ya quedó escrito. Lester suma cada reporte a su
+// historial, así que solo se reintenta si no le llegó.
+func sendFinalReport(lesterClient pb.LesterServiceClient, report *pb.FinalReport) {
+	err := retryUnsent(context.Background(), "SendFinalReport", "Lester", func(ctx context.Context) error {
+		_, err := lesterClient.SendFinalReport(ctx, report)
+		return err
+	})
+	if err != nil {
+		log.Printf("Error enviando reporte final a Lester: %v", err)
+	} else {
+		log.Println("Reporte final enviado con exito.")
+	}
+}
 
-	// FASE 2: Distraccion
-	var distractionSuccess bool
-	var distractionCharacter string
+// pay registra la transferencia de Michael a payee en el libro y después le
+// paga con receive, junto con el reparto acordado para que verifique su
+// parte. Las dos llamadas usan el mismo payment_id, así que se pueden
+// reintentar sin pagar dos veces. Devuelve la respuesta de payee para el log.
+func pay(ctx context.Context, ledgerClient pb.LedgerServiceClient, agreed *pb.Split, payee string,
+	receive func(context.Context, *pb.PaymentRequest, ...grpc.CallOption) (*pb.PaymentResponse, error)) string {
+	missionID, amount := agreed.MissionId, agreed.Shares[payee]
+	paymentID := "reparto-" + payee
+	err := retry(ctx, "RecordTransfer", "Lester", func(ctx context.Context) error {
+		_, err := ledgerClient.RecordTransfer(ctx, &pb.Transfer{
+			MissionId: missionID,
+			Payer:     "Michael",
+			Payee:     payee,
+			Amount:    amount,
+			PaymentId: paymentID,
+		})
+		return err
+	})
+	if err != nil {
+		log.Printf("Error registrando el pago a %s en el libro: %v", payee, err)
+	}
 
-	// Crear cliente de notificaciones para Fase 3
-	notificationClient := pb.NewNotificationServiceClient(lesterConn)
+	var resp *pb.PaymentResponse
+	err = retry(ctx, "ReceivePayment", payee, func(ctx context.Context) (err error) {
+		resp, err = receive(ctx, &pb.PaymentRequest{
+			Amount:    amount,
+			MissionId: missionID,
+			PaymentId: paymentID,
+			Split:     agreed,
+		})
+		return err
+	})
+	if err != nil {
+		log.Printf("Error pagándole a %s: %v", payee, err)
+		return "Error en el pago"
+	}
+	if !resp.CorrectAmount && resp.ExpectedAmount != 0 {
+		log.Printf("%s rechazó el pago de $%d: esperaba $%d (diferencia %+d)",
+			payee, amount, resp.ExpectedAmount, resp.Difference)
+	}
+	return resp.Message
+}
+
+// writeReport escribe el reporte de la misión en los formatos configurados.
+func writeReport(cfg *config.Config, rep *report.Mission) {
+	paths, err := report.Write(cfg.ReportDir, cfg.ReportFormats, rep)
+	for _, path := range paths {
+		log.Printf("%s generado exitosamente", path)
+	}
+	if err != nil {
+		log.Printf("Error creando reporte: %v", err)
+	}
+}
+
+// toOffer convierte la oferta de Lester al formato de los evaluadores.
+func toOffer(offer *pb.OfferResponse) offers.Offer {
+	return offers.Offer{
+		Loot:            offer.Loot,
+		SuccessFranklin: offer.SuccessFranklin,
+		SuccessTrevor:   offer.SuccessTrevor,
+		PoliceRisk:      offer.PoliceRisk,
+		SplitPolicy:     offer.SplitPolicy,
+	}
+}
+
+// compareEvaluators implementa "michael compare-evaluators [archivo]": pasa
+// el mismo flujo de ofertas (por defecto el de la configuración) por cada
+// estrategia y muestra cuáles aceptaría cada una.
+func compareEvaluators(args []string) {
+	cfg, err := config.Load("michael", nil)
+	if err != nil {
+		log.Fatalf("Error cargando configuración: %v", err)
+	}
+	path := cfg.OffersFile
+	if len(args) > 0 {
+		path = args[0]
+	}
+
+	source, err := offers.Open(path, offers.Options{})
+	if err != nil {
+		log.Fatalf("Error cargando ofertas: %v", err)
+	}
+	defer source.Close()
 
-	if currentOffer.SuccessFranklin > currentOffer.SuccessTrevor {
-		// Franklin hace distraccion
-		distractionCharacter = "Franklin"
-		franklinConn, err := grpc.Dial("localhost:50062", grpc.WithInsecure())
+	var history *evaluator.History
+	if cfg.HistoryFile != "" {
+		history, err = evaluator.OpenHistory(cfg.HistoryFile)
 		if err != nil {
-			log.Fatalf("No se pudo conectar a Franklin: %v", err)
+			log.Fatalf("Error cargando historial: %v", err)
 		}
-		defer franklinConn.Close()
+		defer history.Close()
+	}
 
-		franklinClient := pb.NewMissionServiceClient(franklinConn)
-		distractionSuccess = startDistractionPhase(franklinClient, "Franklin", currentOffer.SuccessFranklin)
-	} else {
-		// Trevor hace distraccion
-		distractionCharacter = "Trevor"
-		trevorConn, err := grpc.Dial("localhost:50063", grpc.WithInsecure())
+	stream := source.Offers()
+	fmt.Printf("%d ofertas de %s, %d misiones en el historial\n", len(stream), path, history.Len())
+	for _, name := range evaluator.Names {
+		eval, err := evaluator.New(name, cfg.Evaluator, history)
 		if err != nil {
-			log.Fatalf("No se pudo conectar a Trevor: %v", err)
+			log.Fatalf("Error cargando evaluador: %v", err)
 		}
-		defer trevorConn.Close()
 
-		trevorClient := pb.NewMissionServiceClient(trevorConn)
-		distractionSuccess = startDistractionPhase(trevorClient, "Trevor", currentOffer.SuccessTrevor)
+		accepted, first := 0, -1
+		var expected float64
+		for i, offer := range stream {
+			if ok, _ := eval.Accept(offer); ok {
+				accepted++
+				expected += offers.ExpectedValue(offer)
+				if first < 0 {
+					first = i
+				}
+			}
+		}
+
+		fmt.Printf("%-20s acepta %3d/%d", name, accepted, len(stream))
+		if first >= 0 {
+			offer := stream[first]
+			fmt.Printf("  primera: #%d (Botin=%d, F=%d%%, T=%d%%, Riesgo=%d%%)  valor esperado medio: $%.0f",
+				first+1, offer.Loot, offer.SuccessFranklin, offer.SuccessTrevor, offer.PoliceRisk,
+				expected/float64(accepted))
+		}
+		fmt.Println()
 	}
+}
 
-	if !distractionSuccess {
-		log.Println("Fase 2 fracasada! Atraco cancelado.")
-		generateFailureReport("Fase 2: Distraccion", distractionCharacter, currentOffer.Loot,
-			"Imprevisto personal durante la mision", missionID)
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "compare-evaluators" {
+		compareEvaluators(os.Args[2:])
 		return
 	}
 
-	log.Println("Fase 2 completada con exito! Procediendo a Fase 3...")
-
-	// FASE 3: Golpe
-	var golpeCharacter string
-	var golpeSuccessRate int32
-	var golpeClient pb.MissionServiceClient
+	cfg, err := config.Load("michael", os.Args[1:])
+	if err != nil {
+		log.Fatalf("Error cargando configuración: %v", err)
+	}
 
-	if distractionCharacter == "Franklin" {
-		// Franklin hizo distraccion, Trevor hace golpe
-		golpeCharacter = "Trevor"
-		golpeSuccessRate = currentOffer.SuccessTrevor
-		trevorConn, err := grpc.Dial("localhost:50063", grpc.WithInsecure())
+	var history *evaluator.History
+	if cfg.HistoryFile != "" {
+		history, err = evaluator.OpenHistory(cfg.HistoryFile)
 		if err != nil {
-			log.Fatalf("No se pudo conectar a Trevor: %v", err)
+			log.Fatalf("Error cargando historial: %v", err)
 		}
-		defer trevorConn.Close()
-		golpeClient = pb.NewMissionServiceClient(trevorConn)
-	} else {
-		// Trevor hizo distraccion, Franklin hace golpe
-		golpeCharacter = "Franklin"
-		golpeSuccessRate = currentOffer.SuccessFranklin
-		franklinConn, err := grpc.Dial("localhost:50062", grpc.WithInsecure())
+		defer history.Close()
+	}
+
+	offerEvaluator, err := evaluator.New(cfg.OfferEvaluator, cfg.Evaluator, history)
+	if err != nil {
+		log.Fatalf("Error cargando configuración: %v", err)
+	}
+
+	strategy, err := planner.New(cfg.PlannerStrategy)
+	if err != nil {
+		log.Fatalf("Error cargando configuración: %v", err)
+	}
+
+	if err := report.Check(cfg.ReportFormats); err != nil {
+		log.Fatalf("Error cargando configuración: %v", err)
+	}
+
+	splitPolicy, err := split.New(cfg.SplitPolicy, cfg.Split)
+	if err != nil {
+		log.Fatalf("Error cargando configuración: %v", err)
+	}
+
+	mission := pipeline.Default()
+	if cfg.MissionFile != "" {
+		mission, err = pipeline.Load(cfg.MissionFile)
 		if err != nil {
-			log.Fatalf("No se pudo conectar a Franklin: %v", err)
+			log.Fatalf("Error cargando misión: %v", err)
 		}
-		defer franklinConn.Close()
-		golpeClient = pb.NewMissionServiceClient(franklinConn)
 	}
+	log.Printf("Misión: %s (%d fases)", mission.Name, len(mission.Phases))
+
+	// Todo el azar del atraco sale de esta semilla
+	if cfg.Seed == 0 {
+		cfg.Seed = seed.Random()
+	}
+	log.Printf("Semilla de la corrida: %d (repetir con -seed %d)", cfg.Seed, cfg.Seed)
 
-	golpeSuccess, totalLoot := startGolpePhase(golpeClient, notificationClient,
-		golpeCharacter, golpeSuccessRate, currentOffer.PoliceRisk, currentOffer.Loot)
+	// ID único por ejecución; se propaga a Lester y al equipo en cada RPC
+	missionID := fmt.Sprintf("%d-%d", time.Now().Unix()%10000, os.Getpid())
+	log.Printf("Iniciando misión %s", missionID)
 
-	if !golpeSuccess {
-		log.Println("Fase 3 fracasada! Atraco cancelado.")
-		generateFailureReport("Fase 3: Golpe", golpeCharacter, totalLoot,
-			"Demasiadas estrellas de busqueda", missionID)
+	// FASE 1: Conexion con Lester
+	lesterConn, err := grpc.Dial(cfg.Lester.Address, grpc.WithInsecure())
+	if err != nil {
+		log.Fatalf("No se pudo conectar a Lester: %v", err)
+	}
+	defer lesterConn.Close()
+
+	lesterClient := pb.NewLesterServiceClient(lesterConn)
+	notificationClient := pb.NewNotificationServiceClient(lesterConn)
+	ledgerClient := pb.NewLedgerServiceClient(lesterConn)
+
+	// Los executors disponibles para las fases de la misión
+	crew := make(crewClients)
+	defer crew.Close()
+
+	// El reporte se va completando durante la misión
+	rep := &report.Mission{ID: missionID, Name: mission.Name, Time: time.Now(), Seed: cfg.Seed}
+
+	var terms *pb.Terms // términos acordados con Lester
+	// phaseSeed da a cada intento de una fase su propia semilla, así un
+	// reintento no repite el mismo resultado
+	attempts := make(map[string]int)
+	phaseSeed := func(phase pipeline.Phase, member planner.Member) int64 {
+		attempts[phase.Name]++
+		return seed.Derive(cfg.Seed, phase.Name, member.Name, strconv.Itoa(attempts[phase.Name]))
+	}
+	runner := pipeline.Runner{Executors: map[string]pipeline.Executor{
+		"distraction": pipeline.ExecutorFunc(func(ctx context.Context, phase pipeline.Phase, member planner.Member) (resp *pb.StatusResponse, err error) {
+			timeline := rep.StartPhase(phase.Label, member.Name)
+			defer func() { timeline.Finish(err) }()
+			client, err := crew.client(member)
+			if err != nil {
+				return nil, err
+			}
+			return startDistractionPhase(ctx, client, missionID, member.Name, phase.RequiredTurns(member),
+				phaseSeed(phase, member), timeline.Observe)
+		}),
+		"golpe": pipeline.ExecutorFunc(func(ctx context.Context, phase pipeline.Phase, member planner.Member) (resp *pb.StatusResponse, err error) {
+			timeline := rep.StartPhase(phase.Label, member.Name)
+			defer func() { timeline.Finish(err) }()
+			client, err := crew.client(member)
+			if err != nil {
+				return nil, err
+			}
+			return startGolpePhase(ctx, client, notificationClient, missionID, member.Name,
+				phase.RequiredTurns(member), terms.PoliceRisk, terms.Loot, phaseSeed(phase, member), timeline.Observe)
+		}),
+	}}
+	if err := mission.Validate(runner.Executors); err != nil {
+		log.Fatalf("Misión inválida: %v", err)
+	}
+
+	// fail deja constancia de una misión que no puede seguir: el reporte
+	// local y el FinalReport a Lester
+	fail := func(phase, character string, lostLoot int32, reason string) {
+		log.Printf("La mision no puede continuar: %s", reason)
+		rep.Outcome = report.OutcomeFailed
+		rep.FailedPhase, rep.FailedCharacter = phase, character
+		rep.LostLoot, rep.Reason = lostLoot, reason
+		writeReport(cfg, rep)
+		sendFinalReport(lesterClient, &pb.FinalReport{
+			MissionOutcome:  "failed",
+			ErrorMessage:    reason,
+			CharacterFailed: character,
+			FailedPhase:     phase,
+			LostLoot:        lostLoot,
+			MissionId:       missionID,
+		})
+	}
+
+	// Negociacion con Lester
+	currentOffer, terms, err := findContract(lesterClient, cfg, missionID, offerEvaluator)
+	if err != nil {
+		fail("Fase 1: Negociacion", "Lester", 0, err.Error())
+		return
+	}
+
+	log.Println("Michael acepto un contrato valido.")
+	log.Printf("Terminos: Botin=%d, Riesgo=%d%%, Lester=%d%%", terms.Loot, terms.PoliceRisk, terms.LesterCut)
+
+	// A partir de aqui el equipo trabaja: una interrupcion aborta la fase en curso
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Planificar quien hace cada fase
+	plan, err := strategy.Plan(mission.PlannerPhases(), buildRoster(cfg, currentOffer))
+	if err != nil {
+		fail("Planificacion", "Michael", terms.Loot, fmt.Sprintf("No se pudo planificar el atraco: %v", err))
+		return
+	}
+	log.Printf("Plan del atraco (%s): %s", cfg.PlannerStrategy, plan)
+
+	// FASES 2..N: las declaradas en la misión
+	outcome := runner.Run(ctx, mission, plan, terms.Loot)
+
+	// El historial guarda la oferta con los términos acordados. Un atraco
+	// que Michael interrumpió no dice nada de la tripulación
+	if ctx.Err() == nil {
+		played := toOffer(currentOffer)
+		played.Loot, played.PoliceRisk = terms.Loot, terms.PoliceRisk
+		if err := history.Record(played, outcome.Success); err != nil {
+			log.Printf("Error guardando historial: %v", err)
+		}
+	}
+
+	if !outcome.Success {
+		fail(outcome.FailedPhase.Label, outcome.FailedMember.Name, outcome.Loot, outcome.Reason)
 		return
 	}
 
-	log.Printf("Fase 3 completada con exito! Botin total: $%d", totalLoot)
+	totalLoot := outcome.Loot
+	log.Printf("Fases completadas con exito! Botin total: $%d", totalLoot)
 	log.Println("Atraco completado con exito! Procediendo a reparto del botin...")
 
 	// FASE 4: Reparto del Botin
-	// Calcular partes
-	ctx := context.Background()
+	// La política de la oferta, o la de Michael, reparte entre Lester,
+	// Michael y cada miembro del plan
+	ctx = context.Background()
+
+	policyName := cfg.SplitPolicy
+	if currentOffer.SplitPolicy != "" {
+		policyName = currentOffer.SplitPolicy
+	}
+	policy, err := split.New(policyName, cfg.Split)
+	if err != nil {
+		log.Printf("La oferta pide un reparto que Michael no conoce (%v); se usa %s", err, cfg.SplitPolicy)
+		policyName = cfg.SplitPolicy
+		policy = splitPolicy
+	}
+
+	input := split.Input{
+		TotalLoot:  totalLoot,
+		LesterCut:  terms.LesterCut,
+		PoliceRisk: terms.PoliceRisk,
+		Members:    []split.Member{{Name: split.Michael, Role: split.RolePlanner}},
+	}
+	for _, slot := range plan.Slots {
+		phase, _ := mission.Phase(slot.Phase.Name)
+		input.Members = append(input.Members, split.Member{
+			Name:      slot.Member.Name,
+			Role:      phase.Executor,
+			ExtraLoot: outcome.Extra[slot.Member.Name],
+		})
+	}
+	shares := policy.Split(input)
 
-	individualShare := totalLoot / 4
-	lesterExtra := totalLoot % 4
-	baseLoot := currentOffer.Loot
+	lesterCut := int32(int64(totalLoot) * int64(terms.LesterCut) / 100)
+	lesterShare := shares[split.Lester]
+	baseLoot := terms.Loot
 	extraLoot := totalLoot - baseLoot
 
 	log.Printf("Botin total a repartir: $%d", totalLoot)
-	log.Printf("Reparto del botin:")
-	log.Printf("  Michael: $%d", individualShare)
-	log.Printf("  Franklin: $%d", individualShare)
-	log.Printf("  Trevor: $%d", individualShare)
-	log.Printf("  Lester: $%d (incluyendo el extra de $%d)", individualShare+lesterExtra, lesterExtra)
+	log.Printf("Reparto del botin (%s):", policyName)
+	for _, member := range input.Members {
+		log.Printf("  %s: $%d", member.Name, shares[member.Name])
+	}
+	log.Printf("  Lester: $%d (%d%% acordado mas el extra de $%d)", lesterShare, terms.LesterCut, lesterShare-lesterCut)
+
+	// El reparto queda en el libro de Lester antes de pagar, para que cada
+	// uno verifique su pago contra él
+	agreed := split.ToProto(missionID, policyName, input, shares)
+	err = retry(ctx, "RecordSplit", "Lester", func(ctx context.Context) error {
+		_, err := ledgerClient.RecordSplit(ctx, agreed)
+		return err
+	})
+	if err != nil {
+		log.Printf("Error registrando el reparto en el libro: %v", err)
+	}
 
 	// Pagos
-	var franklinResp, trevorResp, lesterResp string
-
-	// Pagarle a Franklin
-	franklinConn, err := grpc.Dial("localhost:50062", grpc.WithInsecure())
-	if err == nil {
-		defer franklinConn.Close()
-		franklinPayClient := pb.NewMissionServiceClient(franklinConn)
-		franklinPayResp, err := franklinPayClient.ReceivePayment(ctx, &pb.PaymentRequest{
-			Amount: individualShare,
-		})
+	crewResp := make([]string, len(plan.Slots))
+	for i, slot := range plan.Slots {
+		client, err := crew.client(slot.Member)
 		if err != nil {
-			franklinResp = "Error en el pago"
-		} else {
-			franklinResp = franklinPayResp.Message
+			crewResp[i] = "Error en el pago"
+			continue
 		}
+		crewResp[i] = pay(ctx, ledgerClient, agreed, slot.Member.Name, client.ReceivePayment)
 	}
 
-	// Pagarle a Trevor
-	trevorConn, err := grpc.Dial("localhost:50063", grpc.WithInsecure())
-	if err == nil {
-		defer trevorConn.Close()
-		trevorPayClient := pb.NewMissionServiceClient(trevorConn)
-		trevorPayResp, err := trevorPayClient.ReceivePayment(ctx, &pb.PaymentRequest{
-			Amount: individualShare,
-		})
-		if err != nil {
-			trevorResp = "Error en el pago"
-		} else {
-			trevorResp = trevorPayResp.Message
-		}
+	// Pagarle a Lester
+	lesterResp := pay(ctx, ledgerClient, agreed, split.Lester, lesterClient.ReceivePayment)
+
+	log.Printf("Respuestas de pago:")
+	for i, slot := range plan.Slots {
+		log.Printf("  %s: %s", slot.Member.Name, crewResp[i])
 	}
+	log.Printf("  Lester: %s", lesterResp)
 
-	// Pagarle a Lester
-	lesterPayResp, err := lesterClient.ReceivePayment(ctx, &pb.PaymentRequest{
-		Amount: individualShare + lesterExtra,
-	})
+	balance, err := ledgerClient.GetBalance(ctx, &pb.BalanceRequest{Party: split.Michael})
 	if err != nil {
-		lesterResp = "Error en el pago"
+		log.Printf("Error consultando el libro: %v", err)
 	} else {
-		lesterResp = lesterPayResp.Message
+		log.Printf("Saldo de Michael en el libro: $%d (recibido $%d, pagado $%d)",
+			balance.Balance, balance.Received, balance.Paid)
 	}
 
-	log.Printf("Respuestas de pago:")
-	log.Printf("  Franklin: %s", franklinResp)
-	log.Printf("  Trevor: %s", trevorResp)
-	log.Printf("  Lester: %s", lesterResp)
-
 	// Generar reporte final
-	generateSuccessReport(baseLoot, extraLoot, totalLoot, individualShare, individualShare,
-		individualShare+lesterExtra, lesterExtra, missionID)
+	rep.Outcome = report.OutcomeSuccess
+	rep.BaseLoot, rep.ExtraLoot, rep.TotalLoot = baseLoot, extraLoot, totalLoot
+	rep.LesterCut = terms.LesterCut
+	rep.SplitPolicy = policyName
+	for _, member := range input.Members {
+		rep.Payments = append(rep.Payments, report.Payment{Name: member.Name, Amount: shares[member.Name]})
+	}
+	rep.Payments = append(rep.Payments, report.Payment{
+		Name:   split.Lester,
+		Amount: lesterShare,
+		Note:   fmt.Sprintf("%d%% acordado + $%d resto", terms.LesterCut, lesterShare-lesterCut),
+	})
+	writeReport(cfg, rep)
 
 	// Enviar reporte final a Lester
-	ctx = context.Background()
-	finalReport := &pb.FinalReport{
+	sendFinalReport(lesterClient, &pb.FinalReport{
 		MissionOutcome: "success",
 		TotalLoot:      totalLoot,
-		MichaelShare:   individualShare,
-		FranklinShare:  individualShare,
-		TrevorShare:    individualShare,
-		LesterShare:    individualShare + lesterExtra,
+		MichaelShare:   shares[split.Michael],
+		FranklinShare:  shares["Franklin"],
+		TrevorShare:    shares["Trevor"],
+		LesterShare:    lesterShare,
 		ErrorMessage:   "",
-	}
-
-	_, err = lesterClient.SendFinalReport(ctx, finalReport)
-	if err != nil {
-		log.Printf("Error enviando reporte final a Lester: %v", err)
-	} else {
-		log.Println("Reporte final enviado con exito.")
-	}
+		MissionId:      missionID,
+	})
 
 	log.Println("Mision completada exitosamente!")
 }
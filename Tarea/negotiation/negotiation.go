@@ -0,0 +1,130 @@
+// Package negotiation define cómo regatean Michael y Lester los términos de
+// un trabajo: el porcentaje de Lester y una variante con menos riesgo
+// policial a cambio de menos botín.
+package negotiation
+
+import (
+	pb "Tarea/proto"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Veredictos de Lester a una propuesta.
+const (
+	Accepted = "accepted"
+	Rejected = "rejected"
+	Counter  = "counter"
+)
+
+// Policy es la postura de Lester.
+type Policy struct {
+	StandardCut int32 `yaml:"standard_cut"` // porcentaje que pide de entrada
+	MinCut      int32 `yaml:"min_cut"`      // por debajo rechaza
+	MaxRounds   int32 `yaml:"max_rounds"`   // rondas antes de cortar el regateo
+	// RiskDiscount es el porcentaje del botín que se pierde por cada punto
+	// de riesgo policial que se baja.
+	RiskDiscount float64 `yaml:"risk_discount"`
+	MinRisk      int32   `yaml:"min_risk"` // riesgo mínimo que puede conseguir
+}
+
+// DefaultPolicy reparte como antes: con dos miembros, Lester se lleva un
+// cuarto.
+func DefaultPolicy() Policy {
+	return Policy{StandardCut: 25, MinCut: 15, MaxRounds: 3, RiskDiscount: 1, MinRisk: 20}
+}
+
+// Standard son los términos de entrada de una oferta.
+func (p Policy) Standard(loot, policeRisk int32) *pb.Terms {
+	return &pb.Terms{Loot: loot, PoliceRisk: policeRisk, LesterCut: p.StandardCut}
+}
+
+// LootFor es el botín de la variante de la oferta con el riesgo dado.
+func (p Policy) LootFor(offer *pb.Terms, policeRisk int32) int32 {
+	if policeRisk >= offer.PoliceRisk {
+		return offer.Loot
+	}
+	factor := 1 - p.RiskDiscount*float64(offer.PoliceRisk-policeRisk)/100
+	if factor < 0 {
+		factor = 0
+	}
+	return int32(float64(offer.Loot) * factor)
+}
+
+// Respond evalúa proposal en la ronda round. offer son los términos de
+// entrada y position la última postura de Lester (nil en la primera ronda).
+// Devuelve el veredicto y los términos aceptados o la contraoferta.
+func (p Policy) Respond(offer, position, proposal *pb.Terms, round int32) (string, *pb.Terms) {
+	if position == nil {
+		position = offer
+	}
+	if round > p.MaxRounds || proposal.LesterCut < p.MinCut {
+		return Rejected, position
+	}
+
+	// El riesgo se puede bajar hasta MinRisk; el botín lo fija Lester
+	risk := proposal.PoliceRisk
+	if risk > offer.PoliceRisk || risk <= 0 {
+		risk = offer.PoliceRisk
+	}
+	if risk < p.MinRisk && risk < offer.PoliceRisk {
+		risk = p.MinRisk
+	}
+	terms := &pb.Terms{
+		Loot:       p.LootFor(offer, risk),
+		PoliceRisk: risk,
+		LesterCut:  proposal.LesterCut,
+	}
+
+	if proposal.LesterCut >= position.LesterCut && risk == proposal.PoliceRisk &&
+		(proposal.Loot == 0 || proposal.Loot <= terms.Loot) {
+		return Accepted, terms
+	}
+
+	// Contraoferta: Lester cede la mitad de la diferencia en su porcentaje
+	if proposal.LesterCut < position.LesterCut {
+		terms.LesterCut = (proposal.LesterCut + position.LesterCut + 1) / 2
+	}
+	return Counter, terms
+}
+
+// Bargainer es la postura de Michael.
+type Bargainer struct {
+	TargetCut  int32 `yaml:"target_cut"`  // porcentaje para Lester que propone primero; 0 no lo regatea
+	MaxCut     int32 `yaml:"max_cut"`     // porcentaje máximo que acepta
+	TargetRisk int32 `yaml:"target_risk"` // pide bajar el riesgo hasta aquí; 0 no lo pide
+}
+
+// DefaultBargainer no regatea: Michael acepta los términos de entrada y el
+// reparto queda como antes del regateo.
+func DefaultBargainer() Bargainer {
+	return Bargainer{MaxCut: 25}
+}
+
+// Bargains indica si Michael regatea algo.
+func (b Bargainer) Bargains() bool {
+	return b.TargetCut > 0 || b.TargetRisk > 0
+}
+
+// Open es la primera propuesta de Michael para los términos de entrada.
+func (b Bargainer) Open(standard *pb.Terms) *pb.Terms {
+	cut := b.TargetCut
+	if cut == 0 {
+		cut = standard.LesterCut
+	}
+	proposal := &pb.Terms{Loot: standard.Loot, PoliceRisk: standard.PoliceRisk, LesterCut: cut}
+	if b.TargetRisk > 0 && b.TargetRisk < standard.PoliceRisk {
+		proposal.PoliceRisk = b.TargetRisk
+		proposal.Loot = 0 // que Lester fije el botín de la variante
+	}
+	return proposal
+}
+
+// Reply responde a una contraoferta: la repite tal cual si está dentro de
+// los límites de Michael, para que Lester la acepte, o propone acercarse.
+func (b Bargainer) Reply(last, counter *pb.Terms) *pb.Terms {
+	proposal := proto.Clone(counter).(*pb.Terms)
+	if counter.LesterCut > b.MaxCut {
+		proposal.LesterCut = (last.LesterCut + b.MaxCut + 1) / 2
+	}
+	return proposal
+}
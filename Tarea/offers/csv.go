@@ -0,0 +1,80 @@
+package offers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// csvHeaders es el encabezado esperado de ofertas.csv.
+var csvHeaders = []string{"botin_inicial", "prob_exito_franklin", "prob_exito_trevor", "riesgo_policial"}
+
+func parseCSV(r io.Reader) ([]Offer, []Problem, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1 // las filas con columnas de más o de menos se reportan
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil, fmt.Errorf("archivo vacío")
+	}
+
+	// El encabezado puede traer el BOM que agregan algunas planillas
+	header := records[0]
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+	v := newValidator(csvHeaders)
+	for col, name := range csvHeaders {
+		if col >= len(header) || strings.TrimSpace(header[col]) != name {
+			v.row(1, SeverityWarning, fmt.Sprintf("se esperaba la columna %d como %q", col+1, name))
+		}
+	}
+
+	var offers []Offer
+	for i, row := range records[1:] {
+		rowNum := i + 2
+		if len(row) != len(csvHeaders) {
+			v.row(rowNum, SeverityError, fmt.Sprintf("tiene %d columnas, se esperaban %d", len(row), len(csvHeaders)))
+			continue
+		}
+
+		var values [4]int32
+		valid := true
+		for col, cell := range row {
+			cell = strings.TrimSpace(cell)
+			if cell == "" {
+				v.cell(rowNum, col, "celda vacía")
+				valid = false
+				continue
+			}
+			value, err := strconv.ParseInt(cell, 10, 32)
+			if err != nil {
+				v.cell(rowNum, col, fmt.Sprintf("no es un número entero: %q", cell))
+				valid = false
+				continue
+			}
+			if !v.checkValue(rowNum, col, int32(value)) {
+				valid = false
+				continue
+			}
+			values[col] = int32(value)
+		}
+		if !valid {
+			continue
+		}
+
+		offer := Offer{
+			Loot:            values[0],
+			SuccessFranklin: values[1],
+			SuccessTrevor:   values[2],
+			PoliceRisk:      values[3],
+		}
+		v.duplicate(rowNum, offer)
+		offers = append(offers, offer)
+	}
+	return offers, v.problems, nil
+}
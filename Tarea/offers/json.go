@@ -0,0 +1,51 @@
+package offers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+
+	"Tarea/split"
+)
+
+// jsonFields son los nombres de los campos en el orden de las columnas del CSV.
+var jsonFields = []string{"loot", "success_franklin", "success_trevor", "police_risk"}
+
+// parseJSON lee un arreglo de ofertas, p.ej.
+//
+//	[{"loot": 1070161, "success_franklin": 49, "success_trevor": 57, "police_risk": 63}]
+//
+// Cada oferta puede traer además "split_policy".
+//
+// En los problemas, la fila es la posición de la oferta en el arreglo
+// (desde 1) y el encabezado es el nombre del campo.
+func parseJSON(r io.Reader) ([]Offer, []Problem, error) {
+	var raw []Offer
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, nil, err
+	}
+
+	v := newValidator(jsonFields)
+	var offers []Offer
+	for i, offer := range raw {
+		valid := true
+		for col, value := range []int32{offer.Loot, offer.SuccessFranklin, offer.SuccessTrevor, offer.PoliceRisk} {
+			if !v.checkValue(i+1, col, value) {
+				valid = false
+			}
+		}
+		if offer.SplitPolicy != "" && !slices.Contains(split.Names, offer.SplitPolicy) {
+			v.row(i+1, SeverityError, fmt.Sprintf("política de reparto desconocida %q", offer.SplitPolicy))
+			valid = false
+		}
+		if !valid {
+			continue
+		}
+		v.duplicate(i+1, offer)
+		offers = append(offers, offer)
+	}
+	return offers, v.problems, nil
+}
@@ -0,0 +1,173 @@
+// Package offers define de dónde saca Lester sus contratos.
+//
+// Las fuentes de archivo (CSV y JSON) vigilan el archivo y lo recargan
+// cuando cambia, así se pueden publicar contratos nuevos sin reiniciar a
+// Lester.
+package offers
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Offer es un contrato de Lester.
+type Offer struct {
+	Loot            int32 `json:"loot"`
+	SuccessFranklin int32 `json:"success_franklin"`
+	SuccessTrevor   int32 `json:"success_trevor"`
+	PoliceRisk      int32 `json:"police_risk"`
+	// SplitPolicy es la política de reparto del trabajo (ver split); vacío
+	// deja la de Michael. Solo se puede indicar en ofertas JSON.
+	SplitPolicy string `json:"split_policy,omitempty"`
+}
+
+// Source entrega la lista de ofertas vigente.
+//
+// Offers devuelve una instantánea que no cambia después: una recarga
+// reemplaza la lista completa, nunca la modifica, así que un índice válido
+// en la instantánea sigue siéndolo mientras se use esa misma instantánea.
+type Source interface {
+	Offers() []Offer
+	Close() error
+}
+
+// parseFunc lee las ofertas válidas de un archivo junto con los problemas
+// de las filas descartadas.
+type parseFunc func(r io.Reader) ([]Offer, []Problem, error)
+
+// Options configura una fuente de archivo.
+type Options struct {
+	// Reload es cada cuánto se revisa si el archivo cambió; 0 no lo vigila.
+	Reload time.Duration
+	// Strict rechaza el archivo si tiene algún error en lugar de descartar
+	// las filas con problemas.
+	Strict bool
+}
+
+// Open abre la fuente que corresponde a la extensión de path: .json para
+// JSON y CSV para todo lo demás.
+func Open(path string, opts Options) (Source, error) {
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return NewJSON(path, opts)
+	}
+	return NewCSV(path, opts)
+}
+
+// NewCSV crea una fuente a partir de un CSV con el formato de ofertas.csv.
+func NewCSV(path string, opts Options) (Source, error) {
+	return newFileSource(path, opts, parseCSV)
+}
+
+// NewJSON crea una fuente a partir de un arreglo JSON de ofertas.
+func NewJSON(path string, opts Options) (Source, error) {
+	return newFileSource(path, opts, parseJSON)
+}
+
+type fileSource struct {
+	path   string
+	strict bool
+	parse  parseFunc
+	offers atomic.Pointer[[]Offer]
+
+	modTime time.Time // solo lo toca watch
+	done    chan struct{}
+	once    sync.Once
+}
+
+func newFileSource(path string, opts Options, parse parseFunc) (*fileSource, error) {
+	s := &fileSource{path: path, strict: opts.Strict, parse: parse, done: make(chan struct{})}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo abrir las ofertas: %w", err)
+	}
+	offers, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	s.modTime = info.ModTime()
+	s.offers.Store(&offers)
+
+	if opts.Reload > 0 {
+		go s.watch(opts.Reload)
+	}
+	return s, nil
+}
+
+func (s *fileSource) Offers() []Offer {
+	return *s.offers.Load()
+}
+
+func (s *fileSource) Close() error {
+	s.once.Do(func() { close(s.done) })
+	return nil
+}
+
+func (s *fileSource) load() ([]Offer, error) {
+	file, err := os.Open(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo abrir las ofertas: %w", err)
+	}
+	defer file.Close()
+
+	offers, problems, err := s.parse(file)
+	if err != nil {
+		return nil, fmt.Errorf("ofertas inválidas en %s: %w", s.path, err)
+	}
+
+	report := Report{File: s.path, Offers: len(offers), Problems: problems}
+	if s.strict && report.Errors() > 0 {
+		return nil, fmt.Errorf("%s tiene %d errores (ver lester validate-offers)", s.path, report.Errors())
+	}
+	for _, problem := range problems {
+		if problem.Severity == SeverityError {
+			log.Printf("Advertencia: %s", problem)
+		}
+	}
+	if warnings := len(problems) - report.Errors(); warnings > 0 {
+		log.Printf("Advertencia: %s tiene %d advertencias (ver lester validate-offers)", s.path, warnings)
+	}
+	return offers, nil
+}
+
+// watch revisa la fecha de modificación del archivo cada interval y lo
+// recarga cuando cambia. Si la recarga falla (por ejemplo, el archivo a
+// medio escribir) se mantiene la lista anterior hasta el próximo cambio.
+func (s *fileSource) watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(s.path)
+		if err != nil || info.ModTime().Equal(s.modTime) {
+			continue
+		}
+		s.modTime = info.ModTime()
+
+		offers, err := s.load()
+		if err != nil {
+			log.Printf("Error recargando ofertas, se mantienen las anteriores: %v", err)
+			continue
+		}
+		if len(offers) == 0 {
+			log.Printf("%s no tiene ofertas válidas, se mantienen las anteriores", s.path)
+			continue
+		}
+
+		s.offers.Store(&offers)
+		log.Printf("Ofertas recargadas desde %s: %d ofertas", s.path, len(offers))
+	}
+}
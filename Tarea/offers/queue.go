@@ -0,0 +1,165 @@
+package offers
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+)
+
+// Scorer puntúa una oferta; el Queue ofrece primero las de mayor puntaje.
+type Scorer func(Offer) float64
+
+// Nombres de los scorers incorporados.
+const (
+	ScoreFile          = "file" // orden del archivo
+	ScoreExpectedValue = "expected-value"
+	ScoreLoot          = "loot"
+	ScoreLowRisk       = "low-risk"
+)
+
+// NewScorer devuelve el scorer con el nombre dado. "" equivale a "file".
+func NewScorer(name string) (Scorer, error) {
+	switch name {
+	case ScoreFile, "":
+		return nil, nil
+	case ScoreExpectedValue:
+		return ExpectedValue, nil
+	case ScoreLoot:
+		return func(o Offer) float64 { return float64(o.Loot) }, nil
+	case ScoreLowRisk:
+		return func(o Offer) float64 { return float64(-o.PoliceRisk) }, nil
+	default:
+		return nil, fmt.Errorf("puntaje de ofertas desconocido %q", name)
+	}
+}
+
+// ExpectedValue es botín × probabilidad de éxito del mejor miembro ×
+// (1 − riesgo policial).
+func ExpectedValue(o Offer) float64 {
+	success := o.SuccessFranklin
+	if o.SuccessTrevor > success {
+		success = o.SuccessTrevor
+	}
+	return float64(o.Loot) * float64(success) / 100 * (1 - float64(o.PoliceRisk)/100)
+}
+
+// Queue ordena las ofertas de una fuente: quita las repetidas y las ordena
+// por puntaje. Una recarga puede mover las posiciones, así que los clientes
+// guardan como cursor la última oferta que decidieron y Next retoma después
+// de ella en la cola nueva.
+//
+// Con exclusive, una oferta aceptada (Take) deja de ofrecerse a todos.
+type Queue struct {
+	source    Source
+	score     Scorer
+	exclusive bool
+
+	mu     sync.Mutex
+	from   []Offer // instantánea de la fuente con la que se armó ranked
+	ranked []Offer
+	taken  map[Offer]bool
+	// removed guarda, por cada oferta que una recarga quitó, la que la
+	// seguía en la cola, para retomar después de ella.
+	removed map[Offer]Offer
+}
+
+// NewQueue crea la cola. score puede ser nil para mantener el orden del
+// archivo.
+func NewQueue(source Source, score Scorer, exclusive bool) *Queue {
+	return &Queue{
+		source:    source,
+		score:     score,
+		exclusive: exclusive,
+		taken:     make(map[Offer]bool),
+		removed:   make(map[Offer]Offer),
+	}
+}
+
+// refresh rearma la cola si la fuente se recargó. Requiere q.mu tomado.
+func (q *Queue) refresh() {
+	current := q.source.Offers()
+	if len(current) == len(q.from) && (len(current) == 0 || &current[0] == &q.from[0]) {
+		return
+	}
+	q.from = current
+
+	seen := make(map[Offer]bool, len(current))
+	ranked := make([]Offer, 0, len(current))
+	for _, offer := range current {
+		if !seen[offer] {
+			seen[offer] = true
+			ranked = append(ranked, offer)
+		}
+	}
+	if q.score != nil {
+		sort.SliceStable(ranked, func(i, j int) bool {
+			return q.score(ranked[i]) > q.score(ranked[j])
+		})
+	}
+	for i, offer := range q.ranked {
+		if !seen[offer] && i+1 < len(q.ranked) {
+			q.removed[offer] = q.ranked[i+1]
+		}
+	}
+	q.ranked = ranked
+}
+
+// resume devuelve la posición desde la que sigue un cliente que decidió
+// last. Requiere q.mu tomado.
+func (q *Queue) resume(last Offer) (int, bool) {
+	if i := slices.Index(q.ranked, last); i >= 0 {
+		return i + 1, true
+	}
+	// Se quitó: sigue por la primera de las que venían después que siga
+	// en la cola
+	next, ok := q.removed[last]
+	for hops := 0; ok && hops <= len(q.removed); hops++ {
+		if i := slices.Index(q.ranked, next); i >= 0 {
+			return i, true
+		}
+		next, ok = q.removed[next]
+	}
+	return 0, false
+}
+
+// Next devuelve la primera oferta disponible después de last, su posición y
+// el total de la cola. ok es false si no quedan ofertas. Si last se quitó del
+// archivo, sigue por la oferta que venía después; si last es nil o no se
+// puede ubicar, desde la posición cursor.
+func (q *Queue) Next(last *Offer, cursor int) (offer Offer, position, total int, ok bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.refresh()
+	start := cursor
+	if last != nil {
+		if position, ok := q.resume(*last); ok {
+			start = position
+		}
+	}
+	for position = start; position < len(q.ranked); position++ {
+		offer = q.ranked[position]
+		if !q.taken[offer] {
+			return offer, position, len(q.ranked), true
+		}
+	}
+	return Offer{}, position, len(q.ranked), false
+}
+
+// Take marca la oferta como aceptada. Con exclusive devuelve false si otro
+// cliente ya la había tomado; sin exclusive siempre devuelve true.
+func (q *Queue) Take(offer Offer) bool {
+	if !q.exclusive {
+		return true
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.taken[offer] {
+		return false
+	}
+	q.taken[offer] = true
+	return true
+}
@@ -0,0 +1,53 @@
+package offers
+
+import "testing"
+
+// staticSource es una fuente cuya instantánea se reemplaza a mano, como en
+// una recarga.
+type staticSource struct {
+	offers []Offer
+}
+
+func (s *staticSource) Offers() []Offer { return s.offers }
+func (s *staticSource) Close() error    { return nil }
+
+func TestNextResumesAfterReload(t *testing.T) {
+	a := Offer{Loot: 1, PoliceRisk: 10}
+	b := Offer{Loot: 2, PoliceRisk: 10}
+	c := Offer{Loot: 3, PoliceRisk: 10}
+	d := Offer{Loot: 4, PoliceRisk: 10}
+	inserted := Offer{Loot: 5, PoliceRisk: 10}
+
+	tests := []struct {
+		name   string
+		reload []Offer
+		want   Offer
+	}{
+		{"fila insertada antes", []Offer{inserted, a, b, c, d}, c},
+		{"fila anterior quitada", []Offer{b, c, d}, c},
+		{"orden cambiado", []Offer{c, b, a, d}, a},
+		{"última decidida quitada", []Offer{a, c, d}, c},
+		{"última y siguiente quitadas", []Offer{a, d}, d},
+	}
+	for _, tt := range tests {
+		source := &staticSource{offers: []Offer{a, b, c, d}}
+		queue := NewQueue(source, nil, false)
+
+		// El cliente decide a y b
+		var last *Offer
+		cursor := 0
+		for _, want := range []Offer{a, b} {
+			offer, position, _, ok := queue.Next(last, cursor)
+			if !ok || offer != want {
+				t.Fatalf("%s: Next = %+v, %v; quería %+v", tt.name, offer, ok, want)
+			}
+			last, cursor = &offer, position+1
+		}
+
+		source.offers = tt.reload
+		offer, _, _, ok := queue.Next(last, cursor)
+		if !ok || offer != tt.want {
+			t.Errorf("%s: Next = %+v, %v; quería %+v", tt.name, offer, ok, tt.want)
+		}
+	}
+}
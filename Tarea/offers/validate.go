@@ -0,0 +1,149 @@
+package offers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Gravedad de un problema. Los errores descartan la fila; las advertencias
+// solo se informan.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Problem es un problema encontrado en una fila del archivo de ofertas.
+// Column y Header quedan vacíos si el problema es de la fila completa.
+type Problem struct {
+	Row      int    `json:"row"`
+	Column   int    `json:"column,omitempty"`
+	Header   string `json:"header,omitempty"`
+	Severity string `json:"severity"`
+	Reason   string `json:"reason"`
+}
+
+func (p Problem) String() string {
+	location := fmt.Sprintf("fila %d", p.Row)
+	if p.Column > 0 {
+		location += fmt.Sprintf(", columna %d (%s)", p.Column, p.Header)
+	}
+	return fmt.Sprintf("%s: %s: %s", p.Severity, location, p.Reason)
+}
+
+// Report es el resultado de validar un archivo de ofertas.
+type Report struct {
+	File     string    `json:"file"`
+	Offers   int       `json:"offers"` // ofertas válidas
+	Problems []Problem `json:"problems"`
+}
+
+// Errors cuenta los problemas con gravedad de error.
+func (r *Report) Errors() int {
+	count := 0
+	for _, problem := range r.Problems {
+		if problem.Severity == SeverityError {
+			count++
+		}
+	}
+	return count
+}
+
+// WriteText escribe el reporte en texto, un problema por línea.
+func (r *Report) WriteText(w io.Writer) error {
+	for _, problem := range r.Problems {
+		if _, err := fmt.Fprintf(w, "%s: %s\n", r.File, problem); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "%s: %d ofertas válidas, %d errores, %d advertencias\n",
+		r.File, r.Offers, r.Errors(), len(r.Problems)-r.Errors())
+	return err
+}
+
+// WriteJSON escribe el reporte como un objeto JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// Validate lee el archivo de ofertas y reporta todos sus problemas. Solo
+// devuelve error si el archivo no se puede leer en absoluto.
+func Validate(path string) (*Report, error) {
+	parse := parseCSV
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		parse = parseJSON
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo abrir las ofertas: %w", err)
+	}
+	defer file.Close()
+
+	offers, problems, err := parse(file)
+	if err != nil {
+		return nil, fmt.Errorf("ofertas inválidas en %s: %w", path, err)
+	}
+	return &Report{File: path, Offers: len(offers), Problems: problems}, nil
+}
+
+// validator acumula los problemas de un archivo y detecta ofertas repetidas.
+type validator struct {
+	headers  []string
+	problems []Problem
+	seen     map[Offer]int // fila donde apareció cada oferta por primera vez
+}
+
+func newValidator(headers []string) *validator {
+	return &validator{headers: headers, seen: make(map[Offer]int)}
+}
+
+// cell registra un error en la columna col (desde 0) de la fila row.
+func (v *validator) cell(row, col int, reason string) {
+	header := ""
+	if col < len(v.headers) {
+		header = v.headers[col]
+	}
+	v.problems = append(v.problems, Problem{
+		Row:      row,
+		Column:   col + 1,
+		Header:   header,
+		Severity: SeverityError,
+		Reason:   reason,
+	})
+}
+
+func (v *validator) row(row int, severity, reason string) {
+	v.problems = append(v.problems, Problem{Row: row, Severity: severity, Reason: reason})
+}
+
+// checkValue valida el valor de la columna col (desde 0), que sigue el
+// orden de ofertas.csv: botín y luego tres porcentajes.
+func (v *validator) checkValue(row, col int, value int32) bool {
+	if col == 0 {
+		if value < 0 {
+			v.cell(row, col, fmt.Sprintf("botín negativo: %d", value))
+			return false
+		}
+		return true
+	}
+	if value < 0 || value > 100 {
+		v.cell(row, col, fmt.Sprintf("porcentaje fuera de rango (0-100): %d", value))
+		return false
+	}
+	return true
+}
+
+// duplicate informa si la oferta ya apareció en una fila anterior.
+func (v *validator) duplicate(row int, offer Offer) {
+	if first, exists := v.seen[offer]; exists {
+		v.row(row, SeverityWarning, fmt.Sprintf("oferta repetida de la fila %d", first))
+		return
+	}
+	v.seen[offer] = row
+}
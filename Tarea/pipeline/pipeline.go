@@ -0,0 +1,307 @@
+// Package pipeline describe un atraco como una lista de fases declarada en
+// un archivo YAML y la ejecuta en orden.
+//
+// Cada fase indica qué executor la lleva a cabo (una RPC del equipo), qué
+// miembro la hace, qué criterios debe cumplir para considerarse exitosa y
+// qué hacer si fracasa.
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"Tarea/planner"
+	pb "Tarea/proto"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Políticas de fracaso de una fase.
+const (
+	OnFailureAbort    = "abort"    // el atraco se cancela
+	OnFailureContinue = "continue" // se registra y se sigue con la siguiente fase
+	OnFailureRetry    = "retry"    // se repite la fase hasta Retries veces
+)
+
+// Criteria son condiciones adicionales sobre el estado final de una fase.
+// Los valores cero no se comprueban.
+type Criteria struct {
+	MaxStars     int32 `yaml:"max_stars"`      // terminar con menos estrellas que esto
+	MinExtraLoot int32 `yaml:"min_extra_loot"` // botín extra mínimo conseguido
+}
+
+// Phase es una fase declarada en el archivo de misión.
+type Phase struct {
+	Name     string `yaml:"name"`
+	Label    string `yaml:"label"`    // nombre para reportes, p.ej. "Fase 2: Distraccion"
+	Executor string `yaml:"executor"` // executor registrado que la lleva a cabo
+	// CrewFrom reutiliza al miembro asignado a otra fase anterior en lugar
+	// de pedir uno nuevo al planner.
+	CrewFrom      string   `yaml:"crew_from"`
+	Weight        float64  `yaml:"weight"` // peso para el planner
+	Turns         int32    `yaml:"turns"`  // turnos base; se descuenta la tasa de éxito
+	Success       Criteria `yaml:"success"`
+	OnFailure     string   `yaml:"on_failure"`
+	Retries       int      `yaml:"retries"`
+	FailureReason string   `yaml:"failure_reason"`
+}
+
+// RequiredTurns calcula los turnos de la fase para un miembro.
+func (p Phase) RequiredTurns(member planner.Member) int32 {
+	turns := p.Turns - member.SuccessRate
+	if turns < 1 {
+		turns = 1
+	}
+	return turns
+}
+
+// Mission es un atraco completo.
+type Mission struct {
+	Name   string  `yaml:"name"`
+	Phases []Phase `yaml:"phases"`
+}
+
+// Default es el atraco histórico: distracción y golpe.
+func Default() *Mission {
+	mission := &Mission{
+		Name: "Asalto al Banco",
+		Phases: []Phase{
+			{
+				Name:          "distraction",
+				Label:         "Fase 2: Distraccion",
+				Executor:      "distraction",
+				FailureReason: "Imprevisto personal durante la mision",
+			},
+			{
+				Name:          "golpe",
+				Label:         "Fase 3: Golpe",
+				Executor:      "golpe",
+				FailureReason: "Demasiadas estrellas de busqueda",
+			},
+		},
+	}
+	mission.setDefaults()
+	return mission
+}
+
+// Load lee un archivo de misión.
+func Load(path string) (*Mission, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo abrir la misión: %w", err)
+	}
+	defer file.Close()
+
+	var mission Mission
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&mission); err != nil {
+		return nil, fmt.Errorf("misión inválida en %s: %w", path, err)
+	}
+
+	mission.setDefaults()
+	return &mission, nil
+}
+
+func (m *Mission) setDefaults() {
+	for i := range m.Phases {
+		phase := &m.Phases[i]
+		if phase.Label == "" {
+			phase.Label = phase.Name
+		}
+		// Las fases con crew_from no pasan por el planner
+		if phase.Weight == 0 && phase.CrewFrom == "" {
+			phase.Weight = 1
+		}
+		if phase.Turns == 0 {
+			phase.Turns = 200
+		}
+		if phase.OnFailure == "" {
+			phase.OnFailure = OnFailureAbort
+		}
+		if phase.FailureReason == "" {
+			phase.FailureReason = "Fase " + phase.Name + " fracasada"
+		}
+	}
+}
+
+// Validate comprueba que la misión se pueda ejecutar con los executors dados.
+func (m *Mission) Validate(executors map[string]Executor) error {
+	if len(m.Phases) == 0 {
+		return fmt.Errorf("la misión %q no tiene fases", m.Name)
+	}
+
+	seen := make(map[string]bool)
+	for _, phase := range m.Phases {
+		if phase.Name == "" {
+			return fmt.Errorf("hay una fase sin nombre")
+		}
+		if seen[phase.Name] {
+			return fmt.Errorf("fase %q repetida", phase.Name)
+		}
+		if _, ok := executors[phase.Executor]; !ok {
+			return fmt.Errorf("fase %q: executor desconocido %q", phase.Name, phase.Executor)
+		}
+		if phase.CrewFrom != "" && !seen[phase.CrewFrom] {
+			return fmt.Errorf("fase %q: crew_from debe referirse a una fase anterior, no a %q", phase.Name, phase.CrewFrom)
+		}
+		if phase.CrewFrom != "" && phase.Weight != 0 {
+			return fmt.Errorf("fase %q: weight no se usa con crew_from, el miembro no lo elige el planner", phase.Name)
+		}
+		switch phase.OnFailure {
+		case OnFailureAbort, OnFailureContinue, OnFailureRetry:
+		default:
+			return fmt.Errorf("fase %q: on_failure desconocido %q", phase.Name, phase.OnFailure)
+		}
+		seen[phase.Name] = true
+	}
+	return nil
+}
+
+// PlannerPhases devuelve las fases que necesitan un miembro del planner.
+func (m *Mission) PlannerPhases() []planner.Phase {
+	var phases []planner.Phase
+	for _, phase := range m.Phases {
+		if phase.CrewFrom == "" {
+			phases = append(phases, planner.Phase{Name: phase.Name, Weight: phase.Weight})
+		}
+	}
+	return phases
+}
+
+// Phase devuelve la fase con el nombre dado.
+func (m *Mission) Phase(name string) (Phase, bool) {
+	for _, phase := range m.Phases {
+		if phase.Name == name {
+			return phase, true
+		}
+	}
+	return Phase{}, false
+}
+
+// Executor lleva a cabo una fase con el miembro asignado y devuelve su
+// estado final. Devuelve error si no pudo comunicarse con el equipo; el
+// estado es nil si la fase se interrumpió antes de terminar.
+type Executor interface {
+	Execute(ctx context.Context, phase Phase, member planner.Member) (*pb.StatusResponse, error)
+}
+
+// ExecutorFunc adapta una función a Executor.
+type ExecutorFunc func(ctx context.Context, phase Phase, member planner.Member) (*pb.StatusResponse, error)
+
+func (f ExecutorFunc) Execute(ctx context.Context, phase Phase, member planner.Member) (*pb.StatusResponse, error) {
+	return f(ctx, phase, member)
+}
+
+// Outcome es el resultado de ejecutar una misión.
+type Outcome struct {
+	Success bool
+	Loot    int32 // botín base más el extra de todas las fases exitosas
+	// Extra es el botín extra de las fases exitosas por miembro.
+	Extra map[string]int32
+	// Si Success es false: la fase que hizo fracasar el atraco y quién la hacía.
+	FailedPhase  Phase
+	FailedMember planner.Member
+	Reason       string
+	Err          error // el error de comunicación, si lo hubo
+}
+
+// Runner ejecuta misiones con un conjunto de executors registrados.
+type Runner struct {
+	Executors map[string]Executor
+}
+
+// Run ejecuta las fases de mission en orden con los miembros de plan.
+func (r *Runner) Run(ctx context.Context, mission *Mission, plan planner.Plan, baseLoot int32) Outcome {
+	outcome := Outcome{Success: true, Loot: baseLoot, Extra: make(map[string]int32)}
+
+	for _, phase := range mission.Phases {
+		crewPhase := phase.Name
+		if phase.CrewFrom != "" {
+			crewPhase = phase.CrewFrom
+		}
+		member, ok := plan.For(crewPhase)
+		if !ok {
+			return r.fail(outcome, phase, member, "No hay miembro asignado a la fase")
+		}
+
+		status, passed, err := r.runPhase(ctx, phase, member)
+		if passed {
+			outcome.Loot += status.ExtraLoot
+			outcome.Extra[member.Name] += status.ExtraLoot
+			log.Printf("%s completada con exito!", phase.Label)
+			continue
+		}
+
+		if ctx.Err() != nil {
+			return r.fail(outcome, phase, member, "Mision abortada por Michael")
+		}
+		if phase.OnFailure == OnFailureContinue {
+			log.Printf("%s fracasada; la mision continua segun su politica", phase.Label)
+			continue
+		}
+		if err != nil {
+			outcome = r.fail(outcome, phase, member, err.Error())
+			outcome.Err = err
+			return outcome
+		}
+		return r.fail(outcome, phase, member, phase.FailureReason)
+	}
+
+	return outcome
+}
+
+// runPhase ejecuta la fase, con reintentos si su política lo pide, y
+// devuelve el último estado, si cumplió los criterios de éxito y el error
+// del último intento.
+func (r *Runner) runPhase(ctx context.Context, phase Phase, member planner.Member) (*pb.StatusResponse, bool, error) {
+	attempts := 1
+	if phase.OnFailure == OnFailureRetry {
+		attempts += phase.Retries
+	}
+
+	var status *pb.StatusResponse
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if attempt > 1 {
+			log.Printf("Reintentando %s (%d/%d)", phase.Label, attempt, attempts)
+		}
+
+		status, err = r.Executors[phase.Executor].Execute(ctx, phase, member)
+		if err != nil {
+			log.Printf("%s: %v", phase.Label, err)
+		} else if phase.passed(status) {
+			return status, true, nil
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return status, false, err
+}
+
+func (p Phase) passed(status *pb.StatusResponse) bool {
+	if status == nil || status.Status != "success" {
+		return false
+	}
+	if p.Success.MaxStars > 0 && status.CurrentStars >= p.Success.MaxStars {
+		log.Printf("%s: termino con %d estrellas (maximo %d)", p.Label, status.CurrentStars, p.Success.MaxStars)
+		return false
+	}
+	if status.ExtraLoot < p.Success.MinExtraLoot {
+		log.Printf("%s: botin extra $%d menor al minimo $%d", p.Label, status.ExtraLoot, p.Success.MinExtraLoot)
+		return false
+	}
+	return true
+}
+
+func (r *Runner) fail(outcome Outcome, phase Phase, member planner.Member, reason string) Outcome {
+	log.Printf("%s fracasada! Atraco cancelado.", phase.Label)
+	outcome.Success = false
+	outcome.FailedPhase = phase
+	outcome.FailedMember = member
+	outcome.Reason = reason
+	return outcome
+}
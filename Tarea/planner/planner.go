@@ -0,0 +1,201 @@
+// Package planner asigna miembros del equipo a las fases de un atraco.
+//
+// Un plan se calcula a partir de las fases del atraco y de un roster con la
+// tasa de éxito que Lester estimó para cada miembro en la oferta actual.
+package planner
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Member es un miembro del equipo disponible para el atraco.
+type Member struct {
+	Name        string // nombre del personaje
+	Address     string // dirección gRPC de su MissionService
+	SuccessRate int32  // tasa de éxito (%) según la oferta
+}
+
+// Phase es una fase que requiere a un miembro del equipo.
+type Phase struct {
+	Name string
+	// Weight indica cuánto pesa la tasa de éxito del miembro en esta fase;
+	// las estrategias que optimizan probabilidad ponen a los mejores en las
+	// fases de mayor peso.
+	Weight float64
+}
+
+// Slot es la asignación de un miembro a una fase.
+type Slot struct {
+	Phase  Phase
+	Member Member
+}
+
+// Plan es el resultado de una estrategia: un miembro distinto por fase, en el
+// orden de las fases.
+type Plan struct {
+	Slots []Slot
+	// Probability es la probabilidad estimada de éxito del plan completo: el
+	// producto de las tasas de éxito, sin los pesos de las fases.
+	Probability float64
+}
+
+// For devuelve el miembro asignado a la fase indicada.
+func (p Plan) For(phase string) (Member, bool) {
+	for _, slot := range p.Slots {
+		if slot.Phase.Name == phase {
+			return slot.Member, true
+		}
+	}
+	return Member{}, false
+}
+
+func (p Plan) String() string {
+	parts := make([]string, len(p.Slots))
+	for i, slot := range p.Slots {
+		parts[i] = fmt.Sprintf("%s=%s (%d%%)", slot.Phase.Name, slot.Member.Name, slot.Member.SuccessRate)
+	}
+	return fmt.Sprintf("%s, probabilidad estimada %.1f%%", strings.Join(parts, ", "), p.Probability*100)
+}
+
+// Strategy decide quién hace cada fase.
+type Strategy interface {
+	Plan(phases []Phase, roster []Member) (Plan, error)
+}
+
+const (
+	StrategyLegacy     = "legacy"
+	StrategyMaxSuccess = "max-success"
+)
+
+// New devuelve la estrategia registrada con ese nombre.
+func New(name string) (Strategy, error) {
+	switch name {
+	case StrategyLegacy, "":
+		return Legacy{}, nil
+	case StrategyMaxSuccess:
+		return MaxSuccess{}, nil
+	}
+	return nil, fmt.Errorf("estrategia de planificación desconocida: %q", name)
+}
+
+// Legacy reproduce la regla histórica de Michael: el de mayor tasa de éxito
+// hace la primera fase, el siguiente la segunda, etc. En caso de empate va
+// primero el que aparece más tarde en el roster (con Franklin y Trevor empatados,
+// Trevor hacía la distracción).
+type Legacy struct{}
+
+func (Legacy) Plan(phases []Phase, roster []Member) (Plan, error) {
+	if err := checkRoster(phases, roster); err != nil {
+		return Plan{}, err
+	}
+
+	ranked := make([]Member, len(roster))
+	for i, member := range roster {
+		ranked[len(roster)-1-i] = member
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].SuccessRate > ranked[j].SuccessRate
+	})
+
+	return newPlan(phases, ranked[:len(phases)]), nil
+}
+
+// MaxSuccess prueba todas las asignaciones posibles y elige la de mayor
+// probabilidad estimada, ponderando cada fase por su peso. Los pesos solo
+// ordenan los planes; Probability no los incluye.
+type MaxSuccess struct{}
+
+func (MaxSuccess) Plan(phases []Phase, roster []Member) (Plan, error) {
+	if err := checkRoster(phases, roster); err != nil {
+		return Plan{}, err
+	}
+
+	var best Plan
+	bestScore := math.Inf(-1)
+	used := make([]bool, len(roster))
+	chosen := make([]Member, 0, len(phases))
+
+	var search func()
+	search = func() {
+		if len(chosen) == len(phases) {
+			if score := logScore(phases, chosen); score > bestScore {
+				bestScore = score
+				best = newPlan(phases, chosen)
+			}
+			return
+		}
+		for i, member := range roster {
+			if used[i] {
+				continue
+			}
+			used[i] = true
+			chosen = append(chosen, member)
+			search()
+			chosen = chosen[:len(chosen)-1]
+			used[i] = false
+		}
+	}
+	search()
+
+	return best, nil
+}
+
+func checkRoster(phases []Phase, roster []Member) error {
+	if len(phases) == 0 {
+		return fmt.Errorf("el atraco no tiene fases")
+	}
+	if len(roster) < len(phases) {
+		return fmt.Errorf("se necesitan %d miembros y solo hay %d disponibles", len(phases), len(roster))
+	}
+	return nil
+}
+
+// rate convierte una tasa de éxito en probabilidad.
+func rate(successRate int32) float64 {
+	if successRate < 0 {
+		return 0
+	}
+	if successRate > 100 {
+		return 1
+	}
+	return float64(successRate) / 100
+}
+
+// probability convierte una tasa de éxito en probabilidad, sin llegar a cero
+// para que los logaritmos sigan definidos.
+func probability(rate int32) float64 {
+	if rate < 1 {
+		rate = 1
+	}
+	if rate > 100 {
+		rate = 100
+	}
+	return float64(rate) / 100
+}
+
+func logScore(phases []Phase, members []Member) float64 {
+	score := 0.0
+	for i, phase := range phases {
+		score += phaseWeight(phase) * math.Log(probability(members[i].SuccessRate))
+	}
+	return score
+}
+
+func phaseWeight(phase Phase) float64 {
+	if phase.Weight <= 0 {
+		return 1
+	}
+	return phase.Weight
+}
+
+func newPlan(phases []Phase, members []Member) Plan {
+	plan := Plan{Slots: make([]Slot, len(phases)), Probability: 1}
+	for i, phase := range phases {
+		plan.Slots[i] = Slot{Phase: phase, Member: members[i]}
+		plan.Probability *= rate(members[i].SuccessRate)
+	}
+	return plan
+}
@@ -0,0 +1,192 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+)
+
+// Render escribe el reporte en el formato indicado (ver Formats).
+func Render(w io.Writer, format string, m *Mission) error {
+	switch format {
+	case "text":
+		_, err := io.WriteString(w, renderText(m))
+		return err
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(m)
+	case "markdown":
+		_, err := io.WriteString(w, renderMarkdown(m))
+		return err
+	case "html":
+		return htmlTemplate.Execute(w, m)
+	default:
+		return fmt.Errorf("formato de reporte desconocido %q", format)
+	}
+}
+
+const rule = "========================================================="
+
+// renderText mantiene el formato histórico de Reporte.txt y agrega la línea
+// de tiempo al final.
+func renderText(m *Mission) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n== REPORTE FINAL DE LA MISION ==\n%s\n", rule, rule)
+	fmt.Fprintf(&b, "Mision: %s #%s\n", m.Name, m.ID)
+	if m.Seed != 0 {
+		fmt.Fprintf(&b, "Semilla: %d\n", m.Seed)
+	}
+
+	if m.Outcome == OutcomeSuccess {
+		b.WriteString("Resultado Global: MISION COMPLETADA CON EXITO!\n\n")
+		b.WriteString("--- REPARTO DEL BOTIN ---\n")
+		fmt.Fprintf(&b, "Botin Base: $%d\n", m.BaseLoot)
+		fmt.Fprintf(&b, "Botin Extra (Habilidades): $%d\n", m.ExtraLoot)
+		fmt.Fprintf(&b, "Botin Total: $%d\n\n", m.TotalLoot)
+		b.WriteString("--------------------------------------------------------\n")
+		if m.SplitPolicy != "" {
+			fmt.Fprintf(&b, "Reparto: %s\n", m.SplitPolicy)
+		}
+		for _, payment := range m.Payments {
+			fmt.Fprintf(&b, "Pago a %s: $%d", payment.Name, payment.Amount)
+			if payment.Note != "" {
+				fmt.Fprintf(&b, " (%s)", payment.Note)
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("--------------------------------------------------------\n")
+		fmt.Fprintf(&b, "Saldo Final de la Operacion: $%d\n", m.TotalLoot)
+	} else {
+		b.WriteString("Resultado Global: MISION FRACASADA\n\n")
+		b.WriteString("--- DETALLES DEL FRACASO ---\n")
+		fmt.Fprintf(&b, "Fase de Fracaso: %s\n", m.FailedPhase)
+		fmt.Fprintf(&b, "Personaje Responsable: %s\n", m.FailedCharacter)
+		fmt.Fprintf(&b, "Botin Perdido: $%d\n", m.LostLoot)
+		fmt.Fprintf(&b, "Motivo: %s\n", m.Reason)
+	}
+
+	if len(m.Phases) > 0 {
+		b.WriteString("\n--- LINEA DE TIEMPO ---\n")
+		for _, phase := range m.Phases {
+			fmt.Fprintf(&b, "%s (%s): %s, %d turnos, max %d estrellas, %s\n",
+				phase.Label, phase.Member, phase.Status, phase.TotalTurns, phase.MaxStars,
+				phase.End.Sub(phase.Start).Round(time.Millisecond))
+			if phase.Error != "" {
+				fmt.Fprintf(&b, "  Error: %s\n", phase.Error)
+			}
+			for _, event := range phase.Events {
+				fmt.Fprintf(&b, "  turno %3d: %s\n", event.Turn, describe(event))
+			}
+		}
+	}
+
+	b.WriteString(rule + "\n")
+	return b.String()
+}
+
+// describe resume un evento en una línea.
+func describe(event Event) string {
+	if event.Ability != "" {
+		return "habilidad: " + event.Ability
+	}
+	return fmt.Sprintf("%s, %d estrellas, +$%d", event.Status, event.Stars, event.ExtraLoot)
+}
+
+func renderMarkdown(m *Mission) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s #%s\n\n", m.Name, m.ID)
+	if m.Seed != 0 {
+		fmt.Fprintf(&b, "Semilla: `%d`\n\n", m.Seed)
+	}
+
+	if m.Outcome == OutcomeSuccess {
+		b.WriteString("**Resultado:** mision completada con exito\n\n")
+		b.WriteString("| Concepto | Monto |\n|---|---:|\n")
+		fmt.Fprintf(&b, "| Botin base | $%d |\n| Botin extra | $%d |\n| Botin total | $%d |\n\n",
+			m.BaseLoot, m.ExtraLoot, m.TotalLoot)
+		b.WriteString("## Reparto\n\n")
+		if m.SplitPolicy != "" {
+			fmt.Fprintf(&b, "Politica: %s\n\n", m.SplitPolicy)
+		}
+		b.WriteString("| Quien | Pago | Nota |\n|---|---:|---|\n")
+		for _, payment := range m.Payments {
+			fmt.Fprintf(&b, "| %s | $%d | %s |\n", payment.Name, payment.Amount, payment.Note)
+		}
+	} else {
+		b.WriteString("**Resultado:** mision fracasada\n\n")
+		fmt.Fprintf(&b, "- Fase: %s\n- Responsable: %s\n- Botin perdido: $%d\n- Motivo: %s\n",
+			m.FailedPhase, m.FailedCharacter, m.LostLoot, m.Reason)
+	}
+
+	if len(m.Phases) > 0 {
+		b.WriteString("\n## Linea de tiempo\n")
+		for _, phase := range m.Phases {
+			fmt.Fprintf(&b, "\n### %s (%s): %s\n\n", phase.Label, phase.Member, phase.Status)
+			if phase.Error != "" {
+				fmt.Fprintf(&b, "Error: `%s`\n\n", phase.Error)
+			}
+			b.WriteString("| Turno | Evento |\n|---:|---|\n")
+			for _, event := range phase.Events {
+				fmt.Fprintf(&b, "| %d/%d | %s |\n", event.Turn, phase.TotalTurns, describe(event))
+			}
+		}
+	}
+	return b.String()
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"describe": describe,
+	"success":  func(outcome string) bool { return outcome == OutcomeSuccess },
+}).Parse(`<!DOCTYPE html>
+<html lang="es">
+<head>
+<meta charset="utf-8">
+<title>{{.Name}} #{{.ID}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+td, th { border: 1px solid #ccc; padding: 4px 8px; }
+.success { color: #080; } .failed { color: #b00; }
+</style>
+</head>
+<body>
+<h1>{{.Name}} #{{.ID}}</h1>
+{{if .Seed}}<p>Semilla: {{.Seed}}</p>{{end}}
+{{if success .Outcome}}
+<p class="success">Mision completada con exito</p>
+<table>
+<tr><th>Botin base</th><td>${{.BaseLoot}}</td></tr>
+<tr><th>Botin extra</th><td>${{.ExtraLoot}}</td></tr>
+<tr><th>Botin total</th><td>${{.TotalLoot}}</td></tr>
+</table>
+<h2>Reparto</h2>
+{{if .SplitPolicy}}<p>Politica: {{.SplitPolicy}}</p>{{end}}
+<table>
+<tr><th>Quien</th><th>Pago</th><th>Nota</th></tr>
+{{range .Payments}}<tr><td>{{.Name}}</td><td>${{.Amount}}</td><td>{{.Note}}</td></tr>
+{{end}}</table>
+{{else}}
+<p class="failed">Mision fracasada</p>
+<table>
+<tr><th>Fase</th><td>{{.FailedPhase}}</td></tr>
+<tr><th>Responsable</th><td>{{.FailedCharacter}}</td></tr>
+<tr><th>Botin perdido</th><td>${{.LostLoot}}</td></tr>
+<tr><th>Motivo</th><td>{{.Reason}}</td></tr>
+</table>
+{{end}}
+{{if .Phases}}<h2>Linea de tiempo</h2>{{end}}
+{{range .Phases}}
+<h3>{{.Label}} ({{.Member}}): {{.Status}}</h3>
+{{if .Error}}<p class="failed">{{.Error}}</p>{{end}}
+<table>
+<tr><th>Turno</th><th>Evento</th></tr>
+{{$total := .TotalTurns}}{{range .Events}}<tr><td>{{.Turn}}/{{$total}}</td><td>{{describe .}}</td></tr>
+{{end}}</table>
+{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,198 @@
+// Package report arma el reporte final de una misión y lo escribe en uno o
+// varios formatos: texto, JSON, Markdown y HTML.
+package report
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	pb "Tarea/proto"
+)
+
+// Resultados de una misión.
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailed  = "failed"
+)
+
+// Payment es un pago del reparto.
+type Payment struct {
+	Name   string `json:"name"`
+	Amount int32  `json:"amount"`
+	Note   string `json:"note,omitempty"`
+}
+
+// Event es un cambio observado en el estado de una fase.
+type Event struct {
+	Time      time.Time `json:"time"`
+	Status    string    `json:"status"`
+	Turn      int32     `json:"turn"`
+	Stars     int32     `json:"stars"`
+	ExtraLoot int32     `json:"extra_loot"`
+	Ability   string    `json:"ability,omitempty"` // habilidad activada en este evento
+}
+
+// Phase es la línea de tiempo de una fase.
+type Phase struct {
+	Label      string    `json:"label"`
+	Member     string    `json:"member"`
+	Status     string    `json:"status"`
+	Error      string    `json:"error,omitempty"`
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	TotalTurns int32     `json:"total_turns"`
+	MaxStars   int32     `json:"max_stars"`
+	Abilities  []string  `json:"abilities,omitempty"`
+	Events     []Event   `json:"events"`
+
+	last *Event // último estado observado
+}
+
+// Observe registra un estado recibido del personaje. Solo agrega un evento
+// cuando cambia el estado o las estrellas o se activa una habilidad, para no
+// tener uno por turno; Finish agrega el último estado.
+func (p *Phase) Observe(status *pb.StatusResponse) {
+	event := Event{
+		Time:      time.Now(),
+		Status:    status.Status,
+		Turn:      status.TurnsCompleted,
+		Stars:     status.CurrentStars,
+		ExtraLoot: status.ExtraLoot,
+	}
+	p.Status = status.Status
+	p.TotalTurns = status.TotalTurns
+	if status.CurrentStars > p.MaxStars {
+		p.MaxStars = status.CurrentStars
+	}
+	p.last = &event
+
+	for _, name := range status.Abilities[min(len(p.Abilities), len(status.Abilities)):] {
+		activated := event
+		activated.Ability = name
+		p.Events = append(p.Events, activated)
+		p.Abilities = append(p.Abilities, name)
+	}
+
+	n := len(p.Events)
+	if n == 0 || p.Events[n-1].Status != event.Status || p.Events[n-1].Stars != event.Stars {
+		p.Events = append(p.Events, event)
+	}
+}
+
+// Finish cierra la fase; err es el error de comunicación, si lo hubo.
+func (p *Phase) Finish(err error) {
+	p.End = time.Now()
+	if n := len(p.Events); p.last != nil && (n == 0 || p.Events[n-1].Turn != p.last.Turn) {
+		p.Events = append(p.Events, *p.last)
+	}
+	if err != nil {
+		p.Error = err.Error()
+		if p.Status == "" || p.Status == "working" || p.Status == "waiting" {
+			p.Status = "error"
+		}
+	}
+}
+
+// Mission es todo lo que se reporta de una misión.
+type Mission struct {
+	ID      string    `json:"id"`
+	Name    string    `json:"name"`
+	Outcome string    `json:"outcome"`
+	Time    time.Time `json:"time"`
+	// Seed es la semilla de la corrida, para repetirla con -seed.
+	Seed int64 `json:"seed,omitempty"`
+
+	BaseLoot  int32 `json:"base_loot"`
+	ExtraLoot int32 `json:"extra_loot"`
+	TotalLoot int32 `json:"total_loot"`
+	LesterCut int32 `json:"lester_cut"` // porcentaje acordado
+	// SplitPolicy es la política con la que se repartió (ver split).
+	SplitPolicy string    `json:"split_policy,omitempty"`
+	Payments    []Payment `json:"payments,omitempty"`
+
+	FailedPhase     string `json:"failed_phase,omitempty"`
+	FailedCharacter string `json:"failed_character,omitempty"`
+	LostLoot        int32  `json:"lost_loot,omitempty"`
+	Reason          string `json:"reason,omitempty"`
+
+	Phases []*Phase `json:"phases"`
+}
+
+// StartPhase agrega una fase a la línea de tiempo.
+func (m *Mission) StartPhase(label, member string) *Phase {
+	phase := &Phase{Label: label, Member: member, Start: time.Now()}
+	m.Phases = append(m.Phases, phase)
+	return phase
+}
+
+// Formatos y la extensión de su archivo.
+var extensions = map[string]string{
+	"text":     "txt",
+	"json":     "json",
+	"markdown": "md",
+	"html":     "html",
+}
+
+// Formats son los formatos disponibles, ordenados.
+func Formats() []string {
+	formats := make([]string, 0, len(extensions))
+	for format := range extensions {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// Check verifica que todos los formatos existan.
+func Check(formats []string) error {
+	for _, format := range formats {
+		if _, ok := extensions[format]; !ok {
+			return fmt.Errorf("formato de reporte desconocido %q (disponibles: %s)",
+				format, strings.Join(Formats(), ", "))
+		}
+	}
+	return nil
+}
+
+// Write escribe el reporte en dir, un archivo "mision-<id>.<ext>" por
+// formato, y devuelve las rutas escritas. Crea dir si no existe.
+func Write(dir string, formats []string, m *Mission) ([]string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("no se pudo crear %s: %w", dir, err)
+	}
+
+	var paths []string
+	for _, format := range formats {
+		ext, ok := extensions[format]
+		if !ok {
+			return paths, fmt.Errorf("formato de reporte desconocido %q", format)
+		}
+
+		path := filepath.Join(dir, fmt.Sprintf("mision-%s.%s", m.ID, ext))
+		if err := writeFile(path, format, m); err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+func writeFile(path, format string, m *Mission) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("no se pudo crear el reporte: %w", err)
+	}
+
+	if err := Render(file, format, m); err != nil {
+		file.Close()
+		return fmt.Errorf("error escribiendo %s: %w", path, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("error escribiendo %s: %w", path, err)
+	}
+	return nil
+}
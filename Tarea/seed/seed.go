@@ -0,0 +1,53 @@
+// Package seed reparte el azar de un atraco a partir de una semilla, para
+// poder repetir una corrida exactamente.
+//
+// Cada uso del azar (las ofertas de Lester, el imprevisto de una distracción,
+// las habilidades de un golpe) recibe su propio generador derivado de la
+// semilla y de etiquetas que lo identifican, así el resultado no depende del
+// orden en que los servicios consumen números.
+package seed
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math/rand"
+	"time"
+)
+
+// Derive calcula la semilla de un uso a partir de seed y sus etiquetas. Con
+// seed 0 devuelve 0.
+func Derive(seed int64, labels ...string) int64 {
+	if seed == 0 {
+		return 0
+	}
+	h := fnv.New64a()
+	binary.Write(h, binary.LittleEndian, seed)
+	for _, label := range labels {
+		h.Write([]byte{0})
+		h.Write([]byte(label))
+	}
+	derived := int64(h.Sum64() &^ (1 << 63))
+	if derived == 0 {
+		derived = 1
+	}
+	return derived
+}
+
+// New devuelve el generador de seed y sus etiquetas. Con seed 0 el generador
+// no es reproducible.
+func New(seed int64, labels ...string) *rand.Rand {
+	if seed == 0 {
+		return rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return rand.New(rand.NewSource(Derive(seed, labels...)))
+}
+
+// Random elige una semilla nueva, distinta de 0, para una corrida que no
+// indicó ninguna.
+func Random() int64 {
+	for {
+		if s := rand.Int63(); s != 0 {
+			return s
+		}
+	}
+}
@@ -0,0 +1,207 @@
+// Package split reparte el botín de una misión entre Lester, Michael y el
+// equipo según una política.
+package split
+
+import (
+	"fmt"
+	"sort"
+
+	pb "Tarea/proto"
+)
+
+// Lester y Michael cobran en todas las misiones.
+const (
+	Lester  = "Lester"
+	Michael = "Michael"
+)
+
+// RolePlanner es el rol de Michael; el resto de los miembros tiene como rol
+// el executor de su fase (distraction, golpe, ...).
+const RolePlanner = "planner"
+
+// Member es alguien del equipo que cobra una parte.
+type Member struct {
+	Name      string
+	Role      string
+	ExtraLoot int32 // botín extra que generaron sus habilidades
+}
+
+// Input es lo que una política necesita para repartir.
+type Input struct {
+	TotalLoot  int32
+	LesterCut  int32 // porcentaje acordado con Lester
+	PoliceRisk int32
+	Members    []Member // Michael incluido
+}
+
+// Policy reparte el botín. El resultado tiene una parte por miembro y una
+// para Lester, y suma TotalLoot.
+type Policy interface {
+	Split(in Input) map[string]int32
+}
+
+// Params configura las políticas. Cada una usa solo los campos que le
+// corresponden.
+type Params struct {
+	RoleWeights  map[string]int32 `yaml:"role_weights"`  // role-weighted
+	AbilityBonus int32            `yaml:"ability_bonus"` // ability-bonus: % del extra generado
+}
+
+// DefaultParams le da el doble al golpe y la mitad del extra a quien lo
+// generó.
+func DefaultParams() Params {
+	return Params{
+		RoleWeights:  map[string]int32{RolePlanner: 1, "distraction": 1, "golpe": 2},
+		AbilityBonus: 50,
+	}
+}
+
+// Names son las políticas incorporadas.
+var Names = []string{"equal", "role-weighted", "risk-weighted", "commission", "ability-bonus"}
+
+// New devuelve la política con el nombre dado. "" equivale a "equal", el
+// reparto histórico.
+func New(name string, params Params) (Policy, error) {
+	switch name {
+	case "equal", "":
+		return weighted{weight: func(Member, Input) int64 { return 1 }}, nil
+	case "role-weighted":
+		return weighted{weight: func(m Member, _ Input) int64 {
+			if w, ok := params.RoleWeights[m.Role]; ok {
+				return int64(w)
+			}
+			return 1
+		}}, nil
+	case "risk-weighted":
+		// Quien da la cara en el golpe cobra más cuanto más riesgo hubo
+		return weighted{weight: func(m Member, in Input) int64 {
+			if m.Role == "golpe" {
+				return 100 + int64(in.PoliceRisk)
+			}
+			return 100
+		}}, nil
+	case "commission":
+		return weighted{weight: func(Member, Input) int64 { return 1 }, remainderTo: Michael}, nil
+	case "ability-bonus":
+		return abilityBonus{percent: params.AbilityBonus}, nil
+	default:
+		return nil, fmt.Errorf("política de reparto desconocida %q", name)
+	}
+}
+
+// weighted le da a Lester su porcentaje y reparte el resto según el peso de
+// cada miembro. Lo que no se divide va a remainderTo, o a Lester si está
+// vacío, como en el reparto histórico.
+type weighted struct {
+	weight      func(Member, Input) int64
+	remainderTo string
+}
+
+func (w weighted) Split(in Input) map[string]int32 {
+	shares := map[string]int32{Lester: lesterCut(in)}
+	w.distribute(in, shares, in.TotalLoot-shares[Lester])
+	return shares
+}
+
+// distribute suma a shares el reparto de rest entre los miembros.
+func (w weighted) distribute(in Input, shares map[string]int32, rest int32) {
+	var total int64
+	for _, m := range in.Members {
+		total += w.weight(m, in)
+	}
+	paid := int32(0)
+	for _, m := range in.Members {
+		share := int32(0)
+		if total > 0 {
+			share = int32(int64(rest) * w.weight(m, in) / total)
+		}
+		shares[m.Name] += share
+		paid += share
+	}
+
+	to := w.remainderTo
+	if to == "" {
+		to = Lester
+	}
+	shares[to] += rest - paid
+}
+
+// abilityBonus paga primero a cada miembro percent% del extra que generó y
+// reparte el resto en partes iguales.
+type abilityBonus struct {
+	percent int32
+}
+
+func (a abilityBonus) Split(in Input) map[string]int32 {
+	shares := map[string]int32{Lester: lesterCut(in)}
+	rest := in.TotalLoot - shares[Lester]
+	for _, m := range in.Members {
+		bonus := min(int32(int64(m.ExtraLoot)*int64(a.percent)/100), rest)
+		shares[m.Name] = bonus
+		rest -= bonus
+	}
+	weighted{weight: func(Member, Input) int64 { return 1 }}.distribute(in, shares, rest)
+	return shares
+}
+
+func lesterCut(in Input) int32 {
+	return int32(int64(in.TotalLoot) * int64(in.LesterCut) / 100)
+}
+
+// ToProto arma el reparto para el libro de pagos, con los datos necesarios
+// para recalcularlo.
+func ToProto(missionID, policy string, in Input, shares map[string]int32) *pb.Split {
+	s := &pb.Split{
+		MissionId:  missionID,
+		Payer:      Michael,
+		TotalLoot:  in.TotalLoot,
+		LesterCut:  in.LesterCut,
+		Shares:     shares,
+		Policy:     policy,
+		PoliceRisk: in.PoliceRisk,
+	}
+	for _, m := range in.Members {
+		s.Members = append(s.Members, &pb.SplitMember{Name: m.Name, Role: m.Role, ExtraLoot: m.ExtraLoot})
+	}
+	return s
+}
+
+// Check recalcula el reparto registrado con su política y params y devuelve
+// un error si alguna parte no coincide.
+func Check(s *pb.Split, params Params) error {
+	policy, err := New(s.Policy, params)
+	if err != nil {
+		return err
+	}
+
+	in := Input{TotalLoot: s.TotalLoot, LesterCut: s.LesterCut, PoliceRisk: s.PoliceRisk}
+	for _, m := range s.Members {
+		in.Members = append(in.Members, Member{Name: m.Name, Role: m.Role, ExtraLoot: m.ExtraLoot})
+	}
+	expected := policy.Split(in)
+
+	names := make([]string, 0, len(expected))
+	for name := range expected {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if got := s.Shares[name]; got != expected[name] {
+			return fmt.Errorf("según la política %s a %s le tocan $%d, el reparto dice $%d",
+				PolicyName(s.Policy), name, expected[name], got)
+		}
+	}
+	if len(s.Shares) != len(expected) {
+		return fmt.Errorf("el reparto tiene %d partes y la política %s da %d",
+			len(s.Shares), PolicyName(s.Policy), len(expected))
+	}
+	return nil
+}
+
+// PolicyName devuelve el nombre de la política para mostrarlo: "" es equal.
+func PolicyName(name string) string {
+	if name == "" {
+		return "equal"
+	}
+	return name
+}
@@ -0,0 +1,124 @@
+package starbus
+
+import (
+	"context"
+	"log"
+
+	pb "Tarea/proto"
+
+	"google.golang.org/grpc"
+)
+
+// Server expone un bus del proceso como StarBusService, para que otros
+// procesos publiquen y se suscriban sin broker. Lester lo sirve sobre un
+// MemoryBus con -bus grpc.
+type Server struct {
+	pb.UnimplementedStarBusServiceServer
+	bus StarBus
+}
+
+// NewServer crea el servicio sobre bus.
+func NewServer(bus StarBus) *Server {
+	return &Server{bus: bus}
+}
+
+func (s *Server) Publish(ctx context.Context, req *pb.StarMessage) (*pb.PublishResponse, error) {
+	err := s.bus.Publish(req.Character, req.MissionId, Star{Count: req.Count, Turn: req.Turn})
+	if err != nil {
+		return nil, err
+	}
+	return &pb.PublishResponse{}, nil
+}
+
+func (s *Server) Subscribe(req *pb.SubscribeRequest, stream pb.StarBusService_SubscribeServer) error {
+	stars, err := s.bus.Subscribe(stream.Context(), req.Character, req.MissionId)
+	if err != nil {
+		return err
+	}
+	// El encabezado avisa al cliente que la suscripción ya está activa
+	if err := stream.SendHeader(nil); err != nil {
+		return err
+	}
+
+	for star := range stars {
+		err := stream.Send(&pb.StarMessage{
+			Character: req.Character,
+			MissionId: req.MissionId,
+			Count:     star.Count,
+			Turn:      star.Turn,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GRPCBus implementa StarBus sobre el StarBusService de otro proceso.
+type GRPCBus struct {
+	conn   *grpc.ClientConn // nil si la conexión es de quien la creó
+	client pb.StarBusServiceClient
+}
+
+// DialGRPC conecta con el bus que aloja address.
+func DialGRPC(address string) (*GRPCBus, error) {
+	conn, err := grpc.Dial(address, grpc.WithInsecure())
+	if err != nil {
+		return nil, err
+	}
+	return &GRPCBus{conn: conn, client: pb.NewStarBusServiceClient(conn)}, nil
+}
+
+// NewGRPC usa una conexión existente; Close no la cierra.
+func NewGRPC(conn grpc.ClientConnInterface) *GRPCBus {
+	return &GRPCBus{client: pb.NewStarBusServiceClient(conn)}
+}
+
+func (b *GRPCBus) Publish(character, missionID string, star Star) error {
+	_, err := b.client.Publish(context.Background(), &pb.StarMessage{
+		Character: character,
+		MissionId: missionID,
+		Count:     star.Count,
+		Turn:      star.Turn,
+	})
+	return err
+}
+
+// Subscribe vuelve cuando el servidor ya registró la suscripción, así no se
+// pierden estrellas publicadas justo después.
+func (b *GRPCBus) Subscribe(ctx context.Context, character, missionID string) (<-chan Star, error) {
+	stream, err := b.client.Subscribe(ctx, &pb.SubscribeRequest{Character: character, MissionId: missionID})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := stream.Header(); err != nil {
+		return nil, err
+	}
+
+	out := make(chan Star)
+	go func() {
+		defer close(out)
+		for {
+			msg, err := stream.Recv()
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("Se cortó la suscripción a %s: %v", QueueName(character, missionID), err)
+				}
+				return
+			}
+			select {
+			case out <- Star{Count: msg.Count, Turn: msg.Turn}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+func (b *GRPCBus) Close() error {
+	if b.conn == nil {
+		return nil
+	}
+	return b.conn.Close()
+}
@@ -0,0 +1,60 @@
+package starbus
+
+import (
+	"context"
+	"log"
+	"sync"
+)
+
+// MemoryBus implementa StarBus dentro del proceso, sin broker. Solo conecta
+// publicadores y suscriptores que comparten la misma instancia.
+type MemoryBus struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan Star]struct{} // por nombre de cola
+}
+
+func NewMemory() *MemoryBus {
+	return &MemoryBus{subscribers: make(map[string]map[chan Star]struct{})}
+}
+
+func (b *MemoryBus) Publish(character, missionID string, star Star) error {
+	queue := QueueName(character, missionID)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subscribers[queue] {
+		select {
+		case sub <- star:
+		default:
+			log.Printf("Suscriptor de %s saturado, se descarta estrella %d", queue, star.Count)
+		}
+	}
+	return nil
+}
+
+func (b *MemoryBus) Subscribe(ctx context.Context, character, missionID string) (<-chan Star, error) {
+	queue := QueueName(character, missionID)
+	sub := make(chan Star, 16)
+
+	b.mu.Lock()
+	if b.subscribers[queue] == nil {
+		b.subscribers[queue] = make(map[chan Star]struct{})
+	}
+	b.subscribers[queue][sub] = struct{}{}
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		delete(b.subscribers[queue], sub)
+		close(sub)
+		b.mu.Unlock()
+	}()
+
+	return sub, nil
+}
+
+func (b *MemoryBus) Close() error {
+	return nil
+}
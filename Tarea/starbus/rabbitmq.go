@@ -0,0 +1,135 @@
+package starbus
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/streadway/amqp"
+)
+
+// RabbitMQBus implementa StarBus sobre una cola de RabbitMQ por personaje.
+type RabbitMQBus struct {
+	conn *amqp.Connection
+
+	mu        sync.Mutex
+	publishCh *amqp.Channel
+}
+
+func NewRabbitMQ(url string) (*RabbitMQBus, error) {
+	conn, err := amqp.Dial(url)
+	if err != nil {
+		return nil, err
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &RabbitMQBus{conn: conn, publishCh: ch}, nil
+}
+
+// Publish envía "<estrellas>", o "<estrellas>@<turno>" si la estrella lleva
+// turno.
+func (b *RabbitMQBus) Publish(character, missionID string, star Star) error {
+	body := strconv.Itoa(int(star.Count))
+	if star.Turn > 0 {
+		body += "@" + strconv.Itoa(int(star.Turn))
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.publishCh.Publish(
+		"",
+		QueueName(character, missionID),
+		false,
+		false,
+		amqp.Publishing{
+			ContentType: "text/plain",
+			Body:        []byte(body),
+		})
+}
+
+func (b *RabbitMQBus) Subscribe(ctx context.Context, character, missionID string) (<-chan Star, error) {
+	ch, err := b.conn.Channel()
+	if err != nil {
+		return nil, err
+	}
+
+	// Las colas por misión se eliminan al irse su último consumidor.
+	autoDelete := missionID != ""
+	q, err := ch.QueueDeclare(
+		QueueName(character, missionID),
+		false, autoDelete, false, false, nil,
+	)
+	if err != nil {
+		ch.Close()
+		return nil, err
+	}
+
+	msgs, err := ch.Consume(
+		q.Name, "", true, false, false, false, nil,
+	)
+	if err != nil {
+		ch.Close()
+		return nil, err
+	}
+
+	out := make(chan Star)
+	go func() {
+		defer close(out)
+		defer ch.Close()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-msgs:
+				if !ok {
+					return
+				}
+				star, err := parseStar(string(msg.Body))
+				if err != nil {
+					log.Printf("Mensaje de estrellas inválido en %s: %q", q.Name, msg.Body)
+					continue
+				}
+				select {
+				case out <- star:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+func parseStar(body string) (Star, error) {
+	count, turn, hasTurn := strings.Cut(body, "@")
+	stars, err := strconv.Atoi(count)
+	if err != nil {
+		return Star{}, err
+	}
+	star := Star{Count: int32(stars)}
+	if hasTurn {
+		t, err := strconv.Atoi(turn)
+		if err != nil {
+			return Star{}, err
+		}
+		star.Turn = int32(t)
+	}
+	return star, nil
+}
+
+func (b *RabbitMQBus) Close() error {
+	b.mu.Lock()
+	b.publishCh.Close()
+	b.mu.Unlock()
+	return b.conn.Close()
+}
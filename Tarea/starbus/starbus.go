@@ -0,0 +1,59 @@
+// Package starbus define el bus por el que Lester envía las estrellas de
+// búsqueda a los miembros del equipo durante el golpe.
+package starbus
+
+import (
+	"context"
+	"fmt"
+)
+
+const (
+	KindRabbitMQ = "rabbitmq"
+	KindMemory   = "memory" // solo dentro de un proceso, p.ej. en tests
+	KindGRPC     = "grpc"   // sin broker: el bus lo aloja Lester
+)
+
+// Star es una actualización de estrellas.
+type Star struct {
+	Count int32
+	// Turn es el turno del golpe en que la estrella se aplica; 0 la aplica
+	// al llegar. Las corridas reproducibles usan turnos para no depender del
+	// reloj.
+	Turn int32
+}
+
+// StarBus publica y entrega actualizaciones de estrellas por personaje y
+// misión.
+type StarBus interface {
+	// Publish envía el número actual de estrellas al personaje indicado.
+	Publish(character, missionID string, star Star) error
+	// Subscribe entrega las estrellas publicadas para el personaje hasta que
+	// se cancele ctx; el canal devuelto se cierra al terminar.
+	Subscribe(ctx context.Context, character, missionID string) (<-chan Star, error)
+	Close() error
+}
+
+// QueueName es el nombre de la cola asociada a un personaje en una misión.
+// Sin ID de misión se usa la cola histórica "stars_<personaje>".
+func QueueName(character, missionID string) string {
+	if missionID == "" {
+		return "stars_" + character
+	}
+	return "stars_" + character + "_" + missionID
+}
+
+// New conecta con el bus indicado por kind. url es la URL de RabbitMQ o,
+// con grpc, la dirección de Lester. Quien aloja el bus grpc usa NewMemory y
+// NewServer en su lugar.
+func New(kind, url string) (StarBus, error) {
+	switch kind {
+	case KindRabbitMQ, "":
+		return NewRabbitMQ(url)
+	case KindGRPC:
+		return DialGRPC(url)
+	case KindMemory:
+		// Lester y el equipo son procesos distintos: nunca se entregaría nada
+		return nil, fmt.Errorf("el bus %q solo conecta dentro de un proceso; para correr sin broker usar %q", KindMemory, KindGRPC)
+	}
+	return nil, fmt.Errorf("bus de estrellas desconocido: %q", kind)
+}